@@ -15,11 +15,38 @@ var delimReg = regexp.MustCompile(`---\n+`)
 
 // ArticleHeader is a structure that represents the metadata of a Article.
 type ArticleHeader struct {
-	ID      string `yaml:"ID"`
-	Title   string `yaml:"Title"`
-	Tags    string `yaml:"Tags"`
-	Author  string `yaml:"Author"`
-	Private bool   `yaml:"Private"`
+	ID      string `yaml:"ID" json:"id"`
+	Title   string `yaml:"Title" json:"title"`
+	Tags    string `yaml:"Tags" json:"tags"`
+	Author  string `yaml:"Author" json:"author"`
+	Private bool   `yaml:"Private" json:"private"`
+
+	// Organization is the URL name of the organization the article belongs to.
+	Organization string `yaml:"Organization,omitempty" json:"organization,omitempty"`
+	// Slide shows the article in the slide mode. If it is not set, update keeps the mode on Qiita,
+	// so that the files written before Slide was supported do not turn the mode off.
+	Slide *bool `yaml:"Slide,omitempty" json:"slide,omitempty"`
+	// Tweet tweets the article when it is posted. It has no effect on update.
+	Tweet bool `yaml:"Tweet,omitempty" json:"tweet,omitempty"`
+	// PublishAt is the time when publish-due publishes the article, such as "2020-05-12 09:00".
+	PublishAt string `yaml:"PublishAt,omitempty" json:"publish_at,omitempty"`
+}
+
+// slideHeader returns the Slide header of the article whose slide mode is slide.
+// The header is omitted if the mode is off, as the other optional headers are.
+func slideHeader(slide bool) *bool {
+	if !slide {
+		return nil
+	}
+	return &slide
+}
+
+// SlideOr returns the slide mode set in the header, or def if it is not set.
+func (h *ArticleHeader) SlideOr(def bool) bool {
+	if h.Slide == nil {
+		return def
+	}
+	return *h.Slide
 }
 
 // Article is a structure that holds the metadata of a file and the contents of an article.
@@ -54,28 +81,35 @@ func (a *Article) fullContent() (string, error) {
 	return c, nil
 }
 
+// parseArticle splits content into the header and the body.
+// The content without the header is a new article with the empty header.
+func parseArticle(content string) (*ArticleHeader, string, error) {
+	ah := ArticleHeader{}
+	if !strings.HasPrefix(content, "---\n") {
+		return &ah, content, nil
+	}
+	c := delimReg.Split(content, 3)
+	if len(c) != 3 || c[0] != "" {
+		return nil, "", fmt.Errorf("article format is invalid")
+	}
+	if err := yaml.Unmarshal([]byte(c[1]), &ah); err != nil {
+		return nil, "", err
+	}
+	return &ah, c[2], nil
+}
+
 // ArticleFromFile extracts an article from local filesysytem.
 func ArticleFromFile(filepath string) (*Article, error) {
 	b, err := ioutil.ReadFile(filepath)
 	if err != nil {
 		return nil, err
 	}
-	content := string(b)
-	isNew := !strings.HasPrefix(content, "---\n")
-	ah := ArticleHeader{}
-	if !isNew {
-		c := delimReg.Split(content, 3)
-		if len(c) != 3 || c[0] != "" {
-			return nil, fmt.Errorf("article format is invalid")
-		}
-
-		if err := yaml.Unmarshal([]byte(c[1]), &ah); err != nil {
-			return nil, err
-		}
-		content = c[2]
+	ah, content, err := parseArticle(string(b))
+	if err != nil {
+		return nil, err
 	}
 	a := &Article{
-		ArticleHeader: &ah,
+		ArticleHeader: ah,
 		Item:          &Item{Body: content},
 		FilePath:      filepath,
 	}
@@ -0,0 +1,206 @@
+package qiisync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Songmu/flextime"
+)
+
+const (
+	backupDirName         = "backup"
+	backupTimestampFormat = "20060102150405"
+)
+
+// writeFileAtomic writes data to the file at path like ioutil.WriteFile.
+// The data is written to a temporary file in the same directory, which is renamed to path,
+// so that the file is never left truncated even if writing fails halfway.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// backupDir returns the directory where the previous versions of the articles are kept.
+// backup_dir is relative to base_dir.
+func (b *Broker) backupDir() string {
+	if b.Local.BackupDir == "" {
+		return filepath.Join(b.stateDir(), backupDirName)
+	}
+	if filepath.IsAbs(b.Local.BackupDir) {
+		return b.Local.BackupDir
+	}
+	return filepath.Join(b.baseDir(), b.Local.BackupDir)
+}
+
+// articleBackupDir returns the directory of the backups of the article stored at path.
+// The backups of an article that has not been posted yet are kept by its file name.
+func (b *Broker) articleBackupDir(id, path string) string {
+	if id == "" {
+		id = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return filepath.Join(b.backupDir(), id)
+}
+
+// backup keeps the current content of the file at path as a backup of the article,
+// before the file is overwritten with content. Nothing is kept if the file does not exist
+// or has the same content. It returns the path of the backup.
+func (b *Broker) backup(id, path string, content []byte) (string, error) {
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if bytes.Equal(current, content) {
+		return "", nil
+	}
+
+	dir := b.articleBackupDir(id, path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = defaultExtension
+	}
+	ts := flextime.Now().Format(backupTimestampFormat)
+	p := filepath.Join(dir, ts+ext)
+	for n := 1; ; n++ {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			break
+		}
+		p = filepath.Join(dir, fmt.Sprintf("%s-%d%s", ts, n, ext))
+	}
+	if err := writeFileAtomic(p, current, 0644); err != nil {
+		return "", err
+	}
+	Logf("backup", "%s", p)
+	return p, nil
+}
+
+// backupSortKey returns the timestamp and the sequence number in the name of the backup.
+func backupSortKey(name string) (string, int) {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return name, 0
+	}
+	n, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return name, 0
+	}
+	return name[:i], n
+}
+
+// Backups returns the paths of the backups of the article stored at path from newest to oldest.
+func (b *Broker) Backups(path string) ([]string, error) {
+	a, err := ArticleFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := b.articleBackupDir(a.ID, path)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, ni := backupSortKey(names[i])
+		tj, nj := backupSortKey(names[j])
+		if ti != tj {
+			return ti > tj
+		}
+		return ni > nj
+	})
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+	}
+	return paths, nil
+}
+
+// Restore overwrites the article stored at path with the backup.
+// If backup is empty, the newest backup is used. The current content is backed up,
+// so that restoring can be undone.
+func (b *Broker) Restore(path, backup string) (string, error) {
+	backups, err := b.Backups(path)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", errors.New("no backup found")
+	}
+	from := backups[0]
+	if backup != "" {
+		from = ""
+		for _, p := range backups {
+			if filepath.Base(p) == backup || filepath.Base(p) == backup+filepath.Ext(p) {
+				from = p
+				break
+			}
+		}
+		if from == "" {
+			return "", fmt.Errorf("backup not found: %s", backup)
+		}
+	}
+
+	d, err := ioutil.ReadFile(from)
+	if err != nil {
+		return "", err
+	}
+	a, err := ArticleFromFile(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := b.backup(a.ID, path, d); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, d, 0644); err != nil {
+		return "", err
+	}
+	Logf("store", "%s <--- %s", path, from)
+	return from, nil
+}
@@ -0,0 +1,142 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_writeFileAtomic(t *testing.T) {
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	p := filepath.Join(tempDir, "article.md")
+	for _, content := range []string{"first\n", "second\n"} {
+		if err := writeFileAtomic(p, []byte(content), 0644); err != nil {
+			t.Errorf("writeFileAtomic(): %v", err)
+			return
+		}
+		got, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Errorf("read file: %v", err)
+			return
+		}
+		if string(got) != content {
+			t.Errorf("writeFileAtomic() wrote %q, want %q", got, content)
+		}
+	}
+
+	files, err := ioutil.ReadDir(tempDir)
+	if err != nil {
+		t.Errorf("read dir: %v", err)
+		return
+	}
+	if len(files) != 1 {
+		t.Errorf("writeFileAtomic() left %d files, want 1", len(files))
+	}
+
+	if err := writeFileAtomic(filepath.Join(tempDir, "no_such_dir", "article.md"), []byte("x"), 0644); err == nil {
+		t.Errorf("writeFileAtomic() succeeded in a directory that does not exist")
+	}
+}
+
+func TestStoreBackup(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		flextime.Restore()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	p := filepath.Join(b.baseDir(), "20200423", "article.md")
+	article := func(body string) *Article {
+		return &Article{
+			ArticleHeader: &ArticleHeader{ID: "c686397e4a0f4f11683d", Title: "Example title", Tags: "Go"},
+			Item:          &Item{Body: body, UpdatedAt: time.Date(2020, 4, 23, 0, 0, 0, 0, time.UTC)},
+		}
+	}
+	read := func(p string) string {
+		d, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatalf("read file: %v", err)
+		}
+		return string(d)
+	}
+
+	flextime.Fix(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	for _, body := range []string{"# First\n", "# First\n", "# Second\n", "# Third\n"} {
+		if err := b.store(p, article(body)); err != nil {
+			t.Errorf("store(): %v", err)
+			return
+		}
+	}
+
+	backups, err := b.Backups(p)
+	if err != nil {
+		t.Errorf("Backups(): %v", err)
+		return
+	}
+	dir := filepath.Join(b.baseDir(), stateDirName, backupDirName, "c686397e4a0f4f11683d")
+	want := []string{
+		filepath.Join(dir, "20200501120000-1.md"),
+		filepath.Join(dir, "20200501120000.md"),
+	}
+	if diff := cmp.Diff(want, backups); diff != "" {
+		t.Errorf("Backups() mismatch (-want +got):\n%s", diff)
+	}
+	first, _ := article("# First\n").fullContent()
+	if got := read(want[1]); got != first {
+		t.Errorf("oldest backup = %q, want %q", got, first)
+	}
+
+	// The backups are not articles.
+	files, err := b.LocalArticleFiles()
+	if err != nil {
+		t.Errorf("LocalArticleFiles(): %v", err)
+		return
+	}
+	if diff := cmp.Diff([]string{p}, files); diff != "" {
+		t.Errorf("LocalArticleFiles() mismatch (-want +got):\n%s", diff)
+	}
+
+	flextime.Fix(time.Date(2020, 5, 2, 12, 0, 0, 0, time.UTC))
+	from, err := b.Restore(p, "20200501120000")
+	if err != nil {
+		t.Errorf("Restore(): %v", err)
+		return
+	}
+	if from != want[1] {
+		t.Errorf("Restore() restored %s, want %s", from, want[1])
+	}
+	if got := read(p); got != first {
+		t.Errorf("restored article = %q, want %q", got, first)
+	}
+
+	// Restoring can be undone with the newest backup.
+	third, _ := article("# Third\n").fullContent()
+	if _, err := b.Restore(p, ""); err != nil {
+		t.Errorf("Restore(): %v", err)
+		return
+	}
+	if got := read(p); got != third {
+		t.Errorf("restored article = %q, want %q", got, third)
+	}
+
+	if _, err := b.Restore(p, "19990101000000"); err == nil {
+		t.Errorf("Restore() succeeded with a backup that does not exist")
+	}
+}
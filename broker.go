@@ -23,6 +23,8 @@ var (
 	defaultBaseURL      = "https://qiita.com/"
 	defaultItemsPerPage = 20
 	defaultExtension    = ".md"
+	// defaultArticleExtensions are the extensions of the files treated as articles.
+	defaultArticleExtensions = []string{".md", ".markdown"}
 
 	invalidCharacterReg = regexp.MustCompile(`[\\\/?:*"<>|]`)
 )
@@ -32,6 +34,35 @@ var (
 type Broker struct {
 	*Config
 	BaseURL *url.URL
+
+	// Verbose reports the files that are skipped when base_dir is searched for articles.
+	Verbose bool
+
+	// AllowSecrets allows articles that seem to contain sensitive data to be posted.
+	AllowSecrets bool
+
+	// Publish allows UploadFresh to make a private article public without confirmation.
+	Publish bool
+	// ConfirmPublish is called to confirm that a private article is made public
+	// when Publish is false. If it is nil, the article is not made public.
+	ConfirmPublish func(title, url string) (bool, error)
+
+	// DryRun makes UploadFresh check articles without updating them on Qiita.
+	DryRun bool
+}
+
+// APIError is an error response of Qiita API.
+type APIError struct {
+	StatusCode int
+	Status     string
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	return &APIError{StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+func (e *APIError) Error() string {
+	return e.Status
 }
 
 // NewBroker create a Broker.
@@ -47,6 +78,11 @@ func (b *Broker) do(req *http.Request) (*http.Response, error) {
 	return http.DefaultClient.Do(req)
 }
 
+// FetchRemoteArticle extracts the article specified by id from Qiita.
+func (b *Broker) FetchRemoteArticle(id string) (*Article, error) {
+	return b.fetchRemoteArticle(&Article{ArticleHeader: &ArticleHeader{ID: id}})
+}
+
 func (b *Broker) fetchRemoteArticle(a *Article) (*Article, error) {
 	if a.ID == "" {
 		return nil, errors.New("article ID is required")
@@ -63,7 +99,7 @@ func (b *Broker) fetchRemoteArticle(a *Article) (*Article, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status)
+		return nil, newAPIError(resp)
 	}
 
 	var item Item
@@ -76,13 +112,27 @@ func (b *Broker) fetchRemoteArticle(a *Article) (*Article, error) {
 
 // FetchRemoteArticles extracts articles from Qiita.
 func (b *Broker) FetchRemoteArticles() ([]*Article, error) {
+	return b.FetchRemoteArticlesSince(time.Time{})
+}
+
+// FetchRemoteArticlesSince extracts articles from Qiita that have been updated after since.
+//
+// The authenticated_user/items endpoint returns the most recently updated articles first,
+// so paging stops as soon as an article that is not newer than since appears.
+// If since is the zero value, all articles are extracted.
+func (b *Broker) FetchRemoteArticlesSince(since time.Time) ([]*Article, error) {
 	var articles []*Article
 	for i := 1; ; i++ {
 		aarticles, hasNext, err := b.fetchRemoteItemsPerPage(i)
 		if err != nil {
 			return nil, err
 		}
-		articles = append(articles, aarticles...)
+		for _, a := range aarticles {
+			if !a.Item.UpdatedAt.After(since) {
+				return articles, nil
+			}
+			articles = append(articles, a)
+		}
 
 		if !hasNext {
 			break
@@ -105,7 +155,7 @@ func (b *Broker) fetchRemoteItemsPerPage(page int) ([]*Article, bool, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, false, errors.New(resp.Status)
+		return nil, false, newAPIError(resp)
 	}
 
 	var items []*Item
@@ -124,7 +174,7 @@ func (b *Broker) fetchRemoteItemsPerPage(page int) ([]*Article, bool, error) {
 // FetchLocalArticles searches base_dir of local filesystem and extracts articles.
 func (b *Broker) FetchLocalArticles() (articles map[string]*Article, err error) {
 	articles = make(map[string]*Article)
-	fnameList, err := dirwalk(b.baseDir())
+	fnameList, err := b.dirwalk(b.baseDir())
 	if err != nil {
 		return nil, fmt.Errorf("dirwalk %s: %w", fnameList, err)
 	}
@@ -145,11 +195,52 @@ func (b *Broker) FetchLocalArticles() (articles map[string]*Article, err error)
 	return articles, nil
 }
 
-func dirwalk(dir string) ([]string, error) {
+// LocalArticleFiles returns the paths of all files of articles under base_dir,
+// including the articles that have not been posted yet.
+func (b *Broker) LocalArticleFiles() ([]string, error) {
+	return b.dirwalk(b.baseDir())
+}
+
+// dirwalk returns the files of articles under dir. The files that do not have
+// the extensions of articles or are listed in .qiisyncignore of dir are skipped,
+// and so are hidden directories unless include_hidden_dirs is set.
+func (b *Broker) dirwalk(dir string) ([]string, error) {
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
 		return nil, fmt.Errorf("make dir: %w", err)
 	}
+	w, err := b.newWalker(dir)
+	if err != nil {
+		return nil, err
+	}
+	return w.walk(dir)
+}
+
+func (b *Broker) newWalker(dir string) (*walker, error) {
+	ignore, err := loadIgnoreFile(filepath.Join(dir, ignoreFileName))
+	if err != nil {
+		return nil, err
+	}
+	return &walker{
+		root:          dir,
+		backupDir:     filepath.Clean(b.backupDir()),
+		extensions:    b.articleExtensions(),
+		includeHidden: b.Local.IncludeHiddenDirs,
+		ignore:        ignore,
+		verbose:       b.Verbose,
+	}, nil
+}
+
+type walker struct {
+	root          string
+	backupDir     string
+	extensions    map[string]bool
+	includeHidden bool
+	ignore        *ignoreMatcher
+	verbose       bool
+}
+
+func (w *walker) walk(dir string) ([]string, error) {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("read dir: %w", err)
@@ -157,20 +248,88 @@ func dirwalk(dir string) ([]string, error) {
 
 	var paths []string
 	for _, file := range files {
+		p := filepath.Join(dir, file.Name())
+		rel, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
 		if file.IsDir() {
-			p, err := dirwalk(filepath.Join(dir, file.Name()))
+			if skip, reason := w.skipDir(p, rel); skip {
+				w.skip(p, reason)
+				continue
+			}
+			ps, err := w.walk(p)
 			if err != nil {
-				return nil, fmt.Errorf("dirwalk %s: %w", filepath.Join(dir, file.Name()), err)
+				return nil, fmt.Errorf("dirwalk %s: %w", p, err)
 			}
-			paths = append(paths, p...)
+			paths = append(paths, ps...)
+			continue
+		}
+
+		if skip, reason := w.skipFile(p, rel); skip {
+			w.skip(p, reason)
 			continue
 		}
-		paths = append(paths, filepath.Join(dir, file.Name()))
+		paths = append(paths, p)
 	}
 
 	return paths, nil
 }
 
+// skipDir reports whether the directory at path is not searched for articles.
+// The reason is empty for the directories of qiisync itself.
+func (w *walker) skipDir(path, rel string) (bool, string) {
+	name := filepath.Base(path)
+	switch {
+	case name == stateDirName, filepath.Clean(path) == w.backupDir:
+		return true, ""
+	case !w.includeHidden && strings.HasPrefix(name, "."):
+		return true, "hidden directory"
+	case w.ignore.match(rel, true):
+		return true, "ignored by " + ignoreFileName
+	}
+	return false, ""
+}
+
+// skipFile reports whether the file at path is not an article.
+func (w *walker) skipFile(path, rel string) (bool, string) {
+	switch {
+	case rel == ignoreFileName:
+		return true, ""
+	case w.ignore.match(rel, false):
+		return true, "ignored by " + ignoreFileName
+	case !w.extensions[strings.ToLower(filepath.Ext(path))]:
+		return true, "not an article"
+	}
+	return false, ""
+}
+
+// accepts reports whether the file at path is an article that dirwalk returns.
+func (w *walker) accepts(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	dir := w.root
+	for i := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, parts[i])
+		if skip, _ := w.skipDir(dir, strings.Join(parts[:i+1], "/")); skip {
+			return false
+		}
+	}
+	skip, _ := w.skipFile(path, filepath.ToSlash(rel))
+	return !skip
+}
+
+func (w *walker) skip(path, reason string) {
+	if w.verbose && reason != "" {
+		Logf("skip", "%s (%s)", path, reason)
+	}
+}
+
 // NewRequest is a testable NewRequest that wraps http.NewRequest.
 func (b *Broker) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
 	if !strings.HasSuffix(b.BaseURL.Path, "/") {
@@ -214,24 +373,49 @@ func (b *Broker) localPath(a *Article) string {
 // updates the files in the local filesystem.
 func (b *Broker) StoreFresh(localArticles map[string]*Article, remoteArticle *Article) (bool, error) {
 	var localLastModified time.Time
-	path := filepath.Join(b.baseDir(), dateFormat(remoteArticle.Item.CreatedAt), b.storeFileName(remoteArticle))
+	path := b.freshPath(localArticles, remoteArticle)
 
 	a, exists := localArticles[remoteArticle.ID]
 	if exists {
 		localLastModified = a.Item.UpdatedAt
-		path = a.FilePath
 	}
 	if remoteArticle.Item.UpdatedAt.After(localLastModified) {
 		Logf("fresh", "remote=%s > local=%s", remoteArticle.Item.UpdatedAt, localLastModified)
+		if exists {
+			keepLocalHeader(remoteArticle.ArticleHeader, a.ArticleHeader)
+		}
+		if b.downloadImages() {
+			if err := b.DownloadImages(path, remoteArticle); err != nil {
+				return false, err
+			}
+		}
 		if err := b.store(path, remoteArticle); err != nil {
 			return false, err
 		}
+		remoteArticle.FilePath = path
+		if err := b.recordRevision(ActionPull, remoteArticle); err != nil {
+			return false, err
+		}
+		if b.Local.SaveHTML {
+			if err := b.StoreHTML(path, remoteArticle); err != nil {
+				return false, err
+			}
+		}
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// freshPath returns the path where the remote article is stored.
+// The path of the local article is used if exists.
+func (b *Broker) freshPath(localArticles map[string]*Article, remoteArticle *Article) string {
+	if a, exists := localArticles[remoteArticle.ID]; exists {
+		return a.FilePath
+	}
+	return filepath.Join(b.baseDir(), dateFormat(remoteArticle.Item.CreatedAt), b.storeFileName(remoteArticle))
+}
+
 func (b *Broker) store(path string, article *Article) error {
 	Logf("store", "%s", path)
 
@@ -241,21 +425,50 @@ func (b *Broker) store(path string, article *Article) error {
 		return err
 	}
 
-	f, err := os.Create(path)
+	fullContext, err := article.fullContent()
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	fullContext, err := article.fullContent()
-	if err != nil {
+	// The local file may have been edited, so keep it before it is overwritten.
+	if _, err := b.backup(article.ID, path, []byte(fullContext)); err != nil {
+		return fmt.Errorf("backup %s: %w", path, err)
+	}
+	if err := writeFileAtomic(path, []byte(fullContext), 0644); err != nil {
 		return err
 	}
-	_, _ = f.WriteString(fullContext)
 
 	return os.Chtimes(path, article.Item.UpdatedAt, article.Item.UpdatedAt)
 }
 
+// ImportArticle stores the article of Qiita specified by id in base_dir as a new local file.
+// An article that already exists in the local filesystem is not imported.
+func (b *Broker) ImportArticle(id string) (*Article, error) {
+	localArticles, err := b.FetchLocalArticles()
+	if err != nil {
+		return nil, err
+	}
+	if la, exists := localArticles[id]; exists {
+		return nil, fmt.Errorf("article %s already exists in local: %s", id, la.FilePath)
+	}
+
+	a, err := b.FetchRemoteArticle(id)
+	if err != nil {
+		return nil, err
+	}
+	path := b.localPath(a)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("file already exists: %s", path)
+	}
+	if err := b.store(path, a); err != nil {
+		return nil, err
+	}
+	if err := b.recordRevision(ActionPull, a); err != nil {
+		return nil, err
+	}
+	a.FilePath = path
+	return a, nil
+}
+
 func (b *Broker) convertItemsArticles(items []*Item) []*Article {
 	articles := make([]*Article, len(items))
 	fileCount := make(map[string]int, len(items))
@@ -277,6 +490,13 @@ func (b *Broker) convertItemsArticles(items []*Item) []*Article {
 	return articles
 }
 
+// keepLocalHeader copies the fields of the local header that Qiita does not have to the header
+// of the remote article, so that pulling the article does not drop them.
+func keepLocalHeader(remote, local *ArticleHeader) {
+	remote.Tweet = local.Tweet
+	remote.PublishAt = local.PublishAt
+}
+
 func (b *Broker) convertItemsArticle(item *Item) *Article {
 	return &Article{
 		ArticleHeader: &ArticleHeader{
@@ -285,6 +505,9 @@ func (b *Broker) convertItemsArticle(item *Item) *Article {
 			Tags:    unmarshalTag(item.Tags),
 			Author:  item.User.Name,
 			Private: item.Private,
+
+			Organization: item.OrganizationURLName,
+			Slide:        slideHeader(item.Slide),
 		},
 		Item: item,
 	}
@@ -292,24 +515,52 @@ func (b *Broker) convertItemsArticle(item *Item) *Article {
 
 // PostArticle post the article on Qiita.
 func (b *Broker) PostArticle(body *PostItem) error {
+	_, err := b.postArticle(body)
+	return err
+}
+
+// postArticle posts the article on Qiita and returns the article stored in base_dir.
+func (b *Broker) postArticle(body *PostItem) (*Article, error) {
+	a := &Article{
+		ArticleHeader: &ArticleHeader{
+			Title:        body.Title,
+			Tags:         unmarshalTag(body.Tags),
+			Private:      body.Private,
+			Organization: body.OrganizationURLName,
+			Slide:        slideHeader(body.Slide),
+			Tweet:        body.Tweet,
+		},
+		Item:     &Item{Body: body.Body},
+		FilePath: body.FilePath,
+	}
+	if err := b.checkArticle(a); err != nil {
+		return nil, err
+	}
+
+	content, err := b.resolveLocalImages(body.Body, body.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	body.Body = content
+
 	req, err := b.NewRequest(http.MethodPost, "api/v2/items", body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	resp, err := b.do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return errors.New(resp.Status)
+		return nil, newAPIError(resp)
 	}
 
 	var r PostItemResult
 	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return fmt.Errorf("json decode: %w", err)
+		return nil, fmt.Errorf("json decode: %w", err)
 	}
 	Logf("post", "article ---> %s", r.URL)
 
@@ -320,9 +571,13 @@ func (b *Broker) PostArticle(body *PostItem) error {
 			Tags:    unmarshalTag(r.Tags),
 			Author:  r.User.Name,
 			Private: r.Private,
+
+			Organization: r.OrganizationURLName,
+			Slide:        slideHeader(r.Slide),
 		},
 		Item: &Item{
 			ID:        r.ID,
+			URL:       r.URL,
 			Title:     r.Title,
 			Tags:      r.Tags,
 			Body:      r.Body,
@@ -330,43 +585,60 @@ func (b *Broker) PostArticle(body *PostItem) error {
 			CreatedAt: r.CreatedAt,
 			UpdatedAt: r.UpdatedAt,
 			Private:   r.Private,
+			Slide:     r.Slide,
+
+			OrganizationURLName: r.OrganizationURLName,
 		},
 	}
 
 	path := filepath.Join(b.baseDir(), r.CreatedAt.Format("20060102"), b.storeFileName(article))
 	if err := b.store(path, article); err != nil {
+		return nil, err
+	}
+	if err := b.recordRevision(ActionPost, article); err != nil {
+		return nil, err
+	}
+	article.FilePath = path
+	return article, nil
+}
+
+// checkArticle runs the checks that every article must pass before it is posted, updated or
+// made public. The problems with the tags are only logged.
+func (b *Broker) checkArticle(a *Article) error {
+	if err := LintErrors(b.Lint(a)); err != nil {
 		return err
 	}
-	return nil
+	b.checkTags(a)
+	return b.checkSecrets(a)
 }
 
-func (b *Broker) patchArticle(body *PostItem) error {
+func (b *Broker) patchArticle(body *PostItem) (*Item, error) {
 	if body.ID == "" {
-		return errors.New("ID is required")
+		return nil, errors.New("ID is required")
 	}
 	u := fmt.Sprintf("api/v2/items/%s", body.ID)
 	req, err := b.NewRequest(http.MethodPatch, u, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	resp, err := b.do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
+		return nil, newAPIError(resp)
 	}
 
 	var item Item
 	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
-		return err
+		return nil, err
 	}
 
 	Logf("post", "fresh article ---> %s", item.URL)
-	return nil
+	return &item, nil
 }
 
 // UploadFresh posts articles to Qiita.
@@ -385,23 +657,81 @@ func (b *Broker) UploadFresh(a *Article) (bool, error) {
 	if a.Private && !ra.Private {
 		return false, errors.New("once an article has been published, it cannot be privately published")
 	}
+	publish := !a.Private && ra.Private
+
+	if err := b.checkArticle(a); err != nil {
+		return false, err
+	}
+	if b.DryRun {
+		if publish {
+			Logf("publish", "%q (%s) would be made public", ra.Title, ra.Item.URL)
+		}
+		Logf("post", "%s would be pushed ---> %s", a.FilePath, ra.Item.URL)
+		return true, nil
+	}
+
+	// Making an article public cannot be undone in practice, since search engines index it
+	// immediately. So it is confirmed after all the checks that may stop the update, and
+	// before anything is sent, including the local images.
+	if publish {
+		if err := b.confirmPublish(ra); err != nil {
+			return false, err
+		}
+	}
+
+	content, err := b.restoreImageLinks(a)
+	if err != nil {
+		return false, err
+	}
+	content, err = b.resolveLocalImages(content, a.FilePath)
+	if err != nil {
+		return false, err
+	}
 
 	body := &PostItem{
-		Body:    a.Item.Body,
+		Body:    content,
 		Private: a.Private,
 		Tags:    MarshalTag(a.Tags),
 		Title:   a.Title,
+		Slide:   a.SlideOr(ra.Item.Slide),
 		ID:      a.ID,
 		URL:     ra.Item.URL,
+
+		OrganizationURLName: a.Organization,
 	}
 
-	if err := b.patchArticle(body); err != nil {
+	item, err := b.patchArticle(body)
+	if err != nil {
+		return false, err
+	}
+	if publish {
+		Logf("publish", "private ---> public %q %s", ra.Title, ra.Item.URL)
+	}
+	// The local body is recorded rather than the one sent, whose image links are resolved.
+	pushed := &Article{ArticleHeader: a.ArticleHeader, Item: &Item{Body: a.Item.Body, UpdatedAt: item.UpdatedAt}}
+	if err := b.recordRevision(ActionPush, pushed); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
+func (b *Broker) confirmPublish(ra *Article) error {
+	if !b.Publish {
+		if b.ConfirmPublish == nil {
+			return fmt.Errorf("%q (%s) is private. use --publish to make it public", ra.Title, ra.Item.URL)
+		}
+		ok, err := b.ConfirmPublish(ra.Title, ra.Item.URL)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%q (%s) is not made public", ra.Title, ra.Item.URL)
+		}
+	}
+	return nil
+}
+
 func (b *Broker) storeFileName(a *Article) string {
 	var filename string
 	switch b.Local.FileNameMode {
@@ -1,6 +1,8 @@
 package qiisync
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -106,7 +109,11 @@ func Test_fetchRemoteArticle(t *testing.T) {
 					Versions: []string{"0.0.1"},
 				},
 			},
-			Private: false,
+			Private:        false,
+			LikesCount:     100,
+			ReactionsCount: 100,
+			CommentsCount:  100,
+			PageViewsCount: 100,
 		},
 		FilePath: "",
 	}
@@ -235,7 +242,8 @@ func Test_fetchRemoteArticles(t *testing.T) {
 						Versions: []string{"0.0.1"},
 					},
 				},
-				Private: false,
+				Private:        false,
+				PageViewsCount: 100,
 			},
 		},
 		{
@@ -264,7 +272,8 @@ func Test_fetchRemoteArticles(t *testing.T) {
 						Versions: []string{"0.0.1"},
 					},
 				},
-				Private: false,
+				Private:        false,
+				PageViewsCount: 100,
 			},
 		},
 	}
@@ -274,6 +283,42 @@ func Test_fetchRemoteArticles(t *testing.T) {
 	}
 }
 
+func TestFetchRemoteArticlesSince(t *testing.T) {
+	broker, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+	})
+
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		w.Header().Set("Total-Count", "3")
+		pageNum, err := strconv.Atoi(r.FormValue("page"))
+		if err != nil {
+			t.Errorf("convert int: %s, %v", r.FormValue("page"), err)
+			return
+		}
+		switch pageNum {
+		case 1:
+			fmt.Fprint(w, `[{"id": "111", "title": "111", "updated_at": "2020-04-23T00:00:00+00:00"}]`)
+		case 2:
+			fmt.Fprint(w, `[{"id": "222", "title": "222", "updated_at": "2020-04-22T00:00:00+00:00"}]`)
+		default:
+			t.Errorf("page %d must not be requested", pageNum)
+			fmt.Fprint(w, `[]`)
+		}
+	})
+
+	defaultItemsPerPage = 1
+	got, err := broker.FetchRemoteArticlesSince(time.Date(2020, 4, 22, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("FetchRemoteArticlesSince(): %v", err)
+		return
+	}
+	if len(got) != 1 || got[0].ID != "111" {
+		t.Errorf("FetchRemoteArticlesSince() = %v, want only the article 111", got)
+	}
+}
+
 func Test_fetchRemoteItemsPerPage(t *testing.T) {
 	broker, mux, _, teardown := setup()
 	defer teardown()
@@ -367,7 +412,11 @@ func Test_fetchRemoteItemsPerPage(t *testing.T) {
 						Versions: []string{"0.0.1"},
 					},
 				},
-				Private: false,
+				Private:        false,
+				LikesCount:     100,
+				ReactionsCount: 100,
+				CommentsCount:  100,
+				PageViewsCount: 100,
 			},
 		},
 	}
@@ -528,6 +577,53 @@ func TestStoreFresh(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreFreshKeepsLocalHeader(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	path := filepath.Join(b.baseDir(), "20200423", "draft.md")
+	local := &Article{
+		ArticleHeader: &ArticleHeader{
+			ID:        "1234567890abcdefghij",
+			Title:     "Draft",
+			Tags:      "Go",
+			Private:   true,
+			Tweet:     true,
+			PublishAt: "2020-05-12 09:00",
+		},
+		Item:     &Item{Body: "# Draft\n", UpdatedAt: time.Date(2020, 4, 22, 0, 0, 0, 0, time.UTC)},
+		FilePath: path,
+	}
+	remote := &Article{
+		ArticleHeader: &ArticleHeader{ID: "1234567890abcdefghij", Title: "Draft", Tags: "Go", Private: true},
+		Item:          &Item{Body: "# Draft on Qiita\n", UpdatedAt: time.Date(2020, 4, 23, 0, 0, 0, 0, time.UTC)},
+	}
+
+	updated, err := b.StoreFresh(map[string]*Article{local.ID: local}, remote)
+	if err != nil || !updated {
+		t.Errorf("StoreFresh() = %v, %v, want true", updated, err)
+		return
+	}
+	got, err := ArticleFromFile(path)
+	if err != nil {
+		t.Errorf("ArticleFromFile(): %v", err)
+		return
+	}
+	// Qiita does not have Tweet and PublishAt, so they are kept from the local header.
+	if got.PublishAt != "2020-05-12 09:00" || !got.Tweet {
+		t.Errorf("StoreFresh() header = %+v, want PublishAt and Tweet kept", got.ArticleHeader)
+	}
+	if got.Item.Body != "# Draft on Qiita\n" {
+		t.Errorf("StoreFresh() body = %q, want the body on Qiita", got.Item.Body)
+	}
+}
+
 func TestStore(t *testing.T) {
 	tempDir, err := ioutil.TempDir("testdata", "temp")
 	if err != nil {
@@ -744,6 +840,72 @@ func TestPostArticle(t *testing.T) {
 	}
 }
 
+func TestPostArticleOrganization(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	mux.HandleFunc("/api/v2/items", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		var got map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		for k, want := range map[string]interface{}{"organization_url_name": "increments", "slide": true, "tweet": true} {
+			if got[k] != want {
+				t.Errorf("request body %s = %v, want %v", k, got[k], want)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `
+					{
+						"body": "# Example",
+						"created_at": "2000-01-01T00:00:00+00:00",
+						"id": "c686397e4a0f4f11683d",
+						"organization_url_name": "increments",
+						"private": false,
+						"slide": true,
+						"tags": [{"name": "Ruby", "versions": []}],
+						"title": "Example title",
+						"updated_at": "2000-01-01T00:00:00+00:00",
+						"url": "https://localhost/Test/items/c686397e4a0f4f11683d",
+						"user": {"id": "qiita", "name": "Qiita キータ"}
+					}
+`)
+	})
+
+	err := b.PostArticle(&PostItem{
+		Body:                "# Example",
+		Tags:                []*Tag{{Name: "Ruby", Versions: []string{}}},
+		Title:               "Example title",
+		Slide:               true,
+		OrganizationURLName: "increments",
+		Tweet:               true,
+	})
+	if err != nil {
+		t.Errorf("PostArticle(): %v", err)
+		return
+	}
+
+	articles, err := b.FetchLocalArticles()
+	if err != nil {
+		t.Errorf("FetchLocalArticles(): %v", err)
+		return
+	}
+	a, ok := articles["c686397e4a0f4f11683d"]
+	if !ok {
+		t.Errorf("posted article is not stored")
+		return
+	}
+	if a.Organization != "increments" || !a.SlideOr(false) || a.Tweet {
+		t.Errorf("stored header: Organization = %q, Slide = %v, Tweet = %v, want %q, true, false", a.Organization, a.SlideOr(false), a.Tweet, "increments")
+	}
+}
+
 func TestPatchArticle(t *testing.T) {
 	b, mux, _, teardown := setup()
 	t.Cleanup(func() {
@@ -809,7 +971,7 @@ func TestPatchArticle(t *testing.T) {
 `)
 	})
 
-	err := b.patchArticle(&PostItem{
+	_, err := b.patchArticle(&PostItem{
 		Body:    "# Example",
 		Private: false,
 		Tags: []*Tag{
@@ -831,7 +993,7 @@ func TestPatchArticleNoID(t *testing.T) {
 	broker, _, _, teardown := setup()
 	defer teardown()
 
-	err := broker.patchArticle(&PostItem{ID: ""})
+	_, err := broker.patchArticle(&PostItem{ID: ""})
 	if err == nil {
 		t.Errorf("expected error occurred if no article ID")
 		return
@@ -848,7 +1010,7 @@ func TestPatchInvalidResponse(t *testing.T) {
 		fmt.Fprint(w, `[{}]`)
 	})
 
-	err := b.patchArticle(&PostItem{
+	_, err := b.patchArticle(&PostItem{
 		Body:    "# Example",
 		Private: false,
 		Tags: []*Tag{
@@ -1006,6 +1168,9 @@ func TestUploadFresh(t *testing.T) {
 			b, mux, _, teardown := setup()
 			t.Cleanup(func() {
 				teardown()
+				if err := os.RemoveAll(b.baseDir()); err != nil {
+					t.Errorf("remove tempDir: %v", err)
+				}
 			})
 
 			mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
@@ -1075,6 +1240,285 @@ func TestUploadFresh(t *testing.T) {
 	}
 }
 
+func TestImportArticle(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `
+					{
+						"body": "# Example",
+						"created_at": "2000-01-01T00:00:00+00:00",
+						"id": "c686397e4a0f4f11683d",
+						"private": true,
+						"tags": [
+							{
+								"name": "Ruby",
+								"versions": []
+							}
+						],
+						"title": "Example title",
+						"updated_at": "2000-01-01T00:00:00+00:00",
+						"url": "https://qiita.com/Qiita/private/c686397e4a0f4f11683d",
+						"user": {
+							"id": "qiita",
+							"name": "Qiita キータ"
+						}
+					}
+`)
+	})
+
+	got, err := b.ImportArticle("c686397e4a0f4f11683d")
+	if err != nil {
+		t.Errorf("ImportArticle(): %v", err)
+		return
+	}
+	wantPath := filepath.Join(b.baseDir(), "20000101", "Example title.md")
+	if got.FilePath != wantPath {
+		t.Errorf("ImportArticle() path = %v, want %v", got.FilePath, wantPath)
+	}
+
+	fbyte, err := ioutil.ReadFile(wantPath)
+	if err != nil {
+		t.Errorf("read file: %s, %v", wantPath, err)
+		return
+	}
+	want := `---
+ID: c686397e4a0f4f11683d
+Title: Example title
+Tags: Ruby
+Author: Qiita キータ
+Private: true
+---
+
+# Example
+`
+	if string(fbyte) != want {
+		t.Errorf("Imported file string: %v, want %v", string(fbyte), want)
+	}
+
+	if _, err := b.ImportArticle("c686397e4a0f4f11683d"); err == nil {
+		t.Errorf("expected error occurred if the article already exists in local")
+	}
+}
+
+func TestUploadFreshPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish bool
+		confirm func(title, url string) (bool, error)
+		body    string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "no_confirmation",
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name:    "publish_flag",
+			publish: true,
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "confirmed",
+			confirm: func(title, url string) (bool, error) { return true, nil },
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "declined",
+			confirm: func(title, url string) (bool, error) { return false, nil },
+			want:    false,
+			wantErr: true,
+		},
+		{
+			// The user must not be asked if the update fails anyway.
+			name: "lint_error",
+			confirm: func(title, url string) (bool, error) {
+				return false, errors.New("asked before the checks")
+			},
+			body:    "```go\nfunc main() {}\n",
+			want:    false,
+			wantErr: true,
+		},
+		{
+			// The local images must not be uploaded if the user declines.
+			name:    "declined_with_image",
+			confirm: func(title, url string) (bool, error) { return false, nil },
+			body:    "# Example\n\n![](./fig.png)\n",
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, mux, serverURL, teardown := setup()
+			t.Cleanup(func() {
+				teardown()
+				if err := os.RemoveAll(b.baseDir()); err != nil {
+					t.Errorf("remove tempDir: %v", err)
+				}
+			})
+			b.Publish = tt.publish
+			b.ConfirmPublish = tt.confirm
+
+			if err := os.MkdirAll(b.baseDir(), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(b.baseDir(), "fig.png"), []byte("fig"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			uploaded := false
+			mux.HandleFunc("/bucket/", func(w http.ResponseWriter, r *http.Request) {
+				uploaded = true
+				w.WriteHeader(http.StatusOK)
+			})
+			b.Uploader = uploaderConfig{PutURL: serverURL + "/bucket", PublicURL: "https://cdn.example.com/qiisync/"}
+
+			patched := false
+			mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "PATCH" {
+					patched = true
+				}
+				fmt.Fprint(w, `
+					{
+						"body": "# Example",
+						"id": "c686397e4a0f4f11683d",
+						"private": true,
+						"title": "Example title",
+						"updated_at": "2020-04-23T05:41:35+00:00",
+						"url": "https://localhost/Test/private/c686397e4a0f4f11683d"
+					}
+`)
+			})
+
+			body := tt.body
+			if body == "" {
+				body = "# Example"
+			}
+			got, err := b.UploadFresh(&Article{
+				ArticleHeader: &ArticleHeader{
+					ID:      "c686397e4a0f4f11683d",
+					Title:   "Example title",
+					Tags:    "Go",
+					Private: false,
+				},
+				Item: &Item{
+					Body:      body,
+					UpdatedAt: time.Date(2020, 4, 23, 05, 41, 36, 0, time.UTC),
+				},
+				FilePath: filepath.Join(b.baseDir(), "test.md"),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UploadFresh() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && strings.Contains(err.Error(), "asked before the checks") {
+				t.Errorf("UploadFresh() asked for the confirmation before the checks")
+			}
+			if got != tt.want || patched != tt.want {
+				t.Errorf("UploadFresh() got = %v, patched = %v, want %v", got, patched, tt.want)
+			}
+			if uploaded && !tt.want {
+				t.Errorf("UploadFresh() uploaded the local image of the article not updated")
+			}
+		})
+	}
+}
+
+func TestUploadFreshSlide(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name   string
+		slide  *bool
+		remote bool
+		want   bool
+	}{
+		{
+			// The files pulled before Slide was supported have no Slide header.
+			name:   "no_header",
+			remote: true,
+			want:   true,
+		},
+		{
+			name:   "header_off",
+			slide:  &off,
+			remote: true,
+			want:   false,
+		},
+		{
+			name:   "header_on",
+			slide:  &on,
+			remote: false,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, mux, _, teardown := setup()
+			t.Cleanup(func() {
+				teardown()
+				if err := os.RemoveAll(b.baseDir()); err != nil {
+					t.Errorf("remove tempDir: %v", err)
+				}
+			})
+
+			var sent *PostItem
+			mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "PATCH" {
+					sent = &PostItem{}
+					if err := json.NewDecoder(r.Body).Decode(sent); err != nil {
+						t.Errorf("json decode: %v", err)
+					}
+				}
+				fmt.Fprintf(w, `
+					{
+						"body": "# Example",
+						"id": "c686397e4a0f4f11683d",
+						"private": false,
+						"slide": %v,
+						"title": "Example title",
+						"updated_at": "2020-04-23T05:41:35+00:00",
+						"url": "https://localhost/Test/items/c686397e4a0f4f11683d"
+					}
+`, tt.remote)
+			})
+
+			if _, err := b.UploadFresh(&Article{
+				ArticleHeader: &ArticleHeader{
+					ID:    "c686397e4a0f4f11683d",
+					Title: "Example title",
+					Tags:  "Go",
+					Slide: tt.slide,
+				},
+				Item: &Item{
+					Body:      "# Example",
+					UpdatedAt: time.Date(2020, 4, 23, 05, 41, 36, 0, time.UTC),
+				},
+			}); err != nil {
+				t.Errorf("UploadFresh(): %v", err)
+				return
+			}
+			if sent == nil {
+				t.Errorf("UploadFresh() did not update the article")
+				return
+			}
+			if sent.Slide != tt.want {
+				t.Errorf("UploadFresh() sent slide = %v, want %v", sent.Slide, tt.want)
+			}
+		})
+	}
+}
+
 func TestStoreFilename(t *testing.T) {
 	type fields struct {
 		config *Config
@@ -1134,29 +1578,62 @@ func Test_dirwalk(t *testing.T) {
 		}
 	})
 
-	os.MkdirAll(filepath.Join(tempDir, "dir_b"), 0777)
-	os.MkdirAll(filepath.Join(tempDir, "dir_c"), 0777)
-
-	f, _ := os.Create(filepath.Join(tempDir, "file_a"))
-	f.Close()
-	f, _ = os.Create(filepath.Join(tempDir, "dir_b", "file_b"))
-	f.Close()
-	f, _ = os.Create(filepath.Join(tempDir, "dir_c", "file_c"))
-	f.Close()
-
-	got, err := dirwalk(baseDir)
+	for _, d := range []string{"dir_b", "dir_c", "drafts", ".git", stateDirName} {
+		os.MkdirAll(filepath.Join(tempDir, d), 0777)
+	}
+	for _, name := range []string{
+		"file_a.md",
+		filepath.Join("dir_b", "file_b.md"),
+		filepath.Join("dir_b", "note.tmp.md"),
+		filepath.Join("dir_b", "keep.tmp.md"),
+		filepath.Join("dir_c", "file_c.Markdown"),
+		filepath.Join("dir_c", "secret.md"),
+		filepath.Join("dir_c", "image.png"),
+		filepath.Join("drafts", "draft.md"),
+		filepath.Join(".git", "HEAD.md"),
+		filepath.Join(".git", "logo.png"),
+		filepath.Join(stateDirName, lastPullFileName),
+		".DS_Store",
+	} {
+		f, _ := os.Create(filepath.Join(tempDir, name))
+		f.Close()
+	}
+	ioutil.WriteFile(filepath.Join(tempDir, ignoreFileName), []byte(`# drafts are not articles
+drafts/
+*.tmp.md
+!keep.tmp.md
+/dir_c/secret.md
+`), 0644)
+
+	b := NewBroker(&Config{})
+	got, err := b.dirwalk(tempDir)
 	if err != nil {
 		t.Errorf("dirwalk: %v", err)
 	}
 	want := []string{
-		filepath.Join(tempDir, "dir_b", "file_b"),
-		filepath.Join(tempDir, "dir_c", "file_c"),
-		filepath.Join(tempDir, "file_a"),
+		filepath.Join(tempDir, "dir_b", "file_b.md"),
+		filepath.Join(tempDir, "dir_b", "keep.tmp.md"),
+		filepath.Join(tempDir, "dir_c", "file_c.Markdown"),
+		filepath.Join(tempDir, "file_a.md"),
 	}
 
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Errorf("dirwalk(%s) mismatch (-want +got):\n%s", tempDir, diff)
 	}
+
+	b.Local.Extensions = []string{"png"}
+	b.Local.IncludeHiddenDirs = true
+	got, err = b.dirwalk(tempDir)
+	if err != nil {
+		t.Errorf("dirwalk: %v", err)
+	}
+	want = []string{
+		filepath.Join(tempDir, ".git", "logo.png"),
+		filepath.Join(tempDir, "dir_c", "image.png"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dirwalk(%s) with extensions mismatch (-want +got):\n%s", tempDir, diff)
+	}
 }
 
 func setup() (broker *Broker, mux *http.ServeMux, serverURL string, teardown func()) {
@@ -2,9 +2,19 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
 	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/d-tsuji/qiisync"
 	"github.com/urfave/cli/v2"
@@ -16,47 +26,382 @@ func main() {
 	app := cli.NewApp()
 	app.Commands = []*cli.Command{
 		commandPull,
+		commandNew,
 		commandPost,
 		commandUpdate,
+		commandPush,
+		commandImport,
+		commandList,
+		commandSearch,
+		commandExport,
+		commandImportDir,
+		commandPreview,
+		commandRender,
+		commandLint,
+		commandComments,
+		commandComment,
+		commandStats,
+		commandTags,
+		commandPublishDue,
+		commandRestore,
+		commandLog,
+		commandShow,
+		commandWatch,
+		commandHook,
+	}
+	app.Flags = []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "report the files skipped when searching for Articles",
+		},
 	}
 	app.Version = qiisync.Version
 	err := app.Run(os.Args)
-	if err != nil {
-		if err != errCommandHelp {
-			qiisync.Logf("error", "%v", err)
-		}
+	if err != nil && err != errCommandHelp {
+		qiisync.Logf("error", "%v", err)
+		os.Exit(1)
 	}
 }
 
+// newBroker creates a Broker with the global flags applied.
+func newBroker(c *cli.Context, conf *qiisync.Config) *qiisync.Broker {
+	b := qiisync.NewBroker(conf)
+	b.Verbose = c.Bool("verbose")
+	return b
+}
+
 var commandPull = &cli.Command{
-	Name:  "pull",
-	Usage: "Pull articles from remote",
+	Name:      "pull",
+	Usage:     "Pull articles from remote",
+	ArgsUsage: "[<id or url>...]",
+	Flags: append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:  "full",
+			Usage: "pull all articles regardless of the last pull",
+		},
+		&cli.BoolFlag{
+			Name:  "download-images",
+			Usage: "download images in articles into the images directory",
+		},
+		&cli.BoolFlag{
+			Name:  "rewrite-image-links",
+			Usage: "download images and rewrite their links to the local copies",
+		},
+		&cli.BoolFlag{
+			Name:  "html",
+			Usage: "store the HTML rendered by Qiita as well",
+		},
+		&cli.BoolFlag{
+			Name:  "comments",
+			Usage: "store the comments on articles in the sidecar files",
+		},
+		&cli.StringFlag{
+			Name:  "git-commit",
+			Usage: "commit the pulled articles to the git repository of base_dir per `MODE`, run or article",
+		},
+	}, filterFlags...),
 	Action: func(c *cli.Context) error {
+		filter, err := articleFilter(c)
+		if err != nil {
+			return err
+		}
+
 		conf, err := qiisync.LoadConfiguration()
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
-		b := qiisync.NewBroker(conf)
-		remoteArticles, err := b.FetchRemoteArticles()
-		if err != nil {
+		if c.Bool("download-images") {
+			conf.Local.DownloadImages = true
+		}
+		if c.Bool("rewrite-image-links") {
+			conf.Local.RewriteImageLinks = true
+		}
+		if c.Bool("html") {
+			conf.Local.SaveHTML = true
+		}
+		if c.Bool("comments") {
+			conf.Local.PullComments = true
+		}
+		if c.IsSet("git-commit") {
+			conf.Git.Commit = c.String("git-commit")
+		}
+		if err := qiisync.ValidateGitCommitMode(conf.Git.Commit); err != nil {
 			return err
 		}
+		b := newBroker(c, conf)
+
 		localArticles, err := b.FetchLocalArticles()
 		if err != nil {
 			return err
 		}
+
+		var pulled []*qiisync.Article
+		// Pull only the specified articles.
+		if c.NArg() > 0 {
+			for _, arg := range c.Args().Slice() {
+				id, err := qiisync.ParseItemID(arg)
+				if err != nil {
+					return err
+				}
+				a, err := b.FetchRemoteArticle(id)
+				if err != nil {
+					return fmt.Errorf("fetch %s: %w", id, err)
+				}
+				if !filter.Match(a) {
+					continue
+				}
+				updated, err := storeArticle(b, localArticles, a)
+				if err != nil {
+					return err
+				}
+				if updated {
+					pulled = append(pulled, a)
+				}
+			}
+			return finishPull(b, pulled)
+		}
+
+		var since time.Time
+		if !c.Bool("full") {
+			since, err = b.LastPulled()
+			if err != nil {
+				return err
+			}
+		}
+		remoteArticles, err := b.FetchRemoteArticlesSince(since)
+		if err != nil {
+			return err
+		}
+		latest := since
+		for i := range remoteArticles {
+			if remoteArticles[i].Item.UpdatedAt.After(latest) {
+				latest = remoteArticles[i].Item.UpdatedAt
+			}
+		}
+		remoteArticles = qiisync.FilterArticles(remoteArticles, filter)
+		fetched := make(map[string]bool, len(remoteArticles))
 		for i := range remoteArticles {
-			if _, err := b.StoreFresh(localArticles, remoteArticles[i]); err != nil {
+			fetched[remoteArticles[i].ID] = true
+			updated, err := storeArticle(b, localArticles, remoteArticles[i])
+			if err != nil {
+				return err
+			}
+			if updated {
+				pulled = append(pulled, remoteArticles[i])
+			}
+		}
+		// Commenting does not change updated_at of the article, so the articles not updated
+		// since the last pull may have new comments as well.
+		if b.Local.PullComments {
+			if err := storeLocalComments(b, localArticles, fetched, filter); err != nil {
+				return err
+			}
+		}
+		// A filtered pull leaves some of the updated articles behind,
+		// so the time of the last pull is kept as it is.
+		if filter.IsZero() && latest.After(since) {
+			if err := b.SaveLastPulled(latest); err != nil {
+				return err
+			}
+		}
+		return finishPull(b, pulled)
+	},
+}
+
+// finishPull updates the search index and commits the pulled articles.
+// The search index is rebuilt on the next search if it fails to be updated.
+func finishPull(b *qiisync.Broker, pulled []*qiisync.Article) error {
+	if err := b.UpdateSearchIndex(); err != nil {
+		qiisync.Logf("error", "update search index: %v", err)
+	}
+	return b.CommitPulled(pulled, b.Git.Commit)
+}
+
+// storeArticle stores the remote article and what comes with it in the local filesystem,
+// and reports whether the article is updated.
+func storeArticle(b *qiisync.Broker, localArticles map[string]*qiisync.Article, a *qiisync.Article) (bool, error) {
+	updated, err := b.StoreFresh(localArticles, a)
+	if err != nil {
+		return false, err
+	}
+	if b.Local.PullComments {
+		if err := b.StoreComments(localArticles, a); err != nil {
+			return false, fmt.Errorf("store comments of %s: %w", a.ID, err)
+		}
+	}
+	return updated, nil
+}
+
+// storeLocalComments stores the comments on the local articles that are not fetched from remote.
+func storeLocalComments(b *qiisync.Broker, localArticles map[string]*qiisync.Article, fetched map[string]bool, filter *qiisync.ArticleFilter) error {
+	ids := make([]string, 0, len(localArticles))
+	for id := range localArticles {
+		if id != "" && !fetched[id] && filter.Match(localArticles[id]) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := b.StoreComments(localArticles, localArticles[id]); err != nil {
+			return fmt.Errorf("store comments of %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+var filterFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:  "tag",
+		Usage: "only articles with the `TAG`",
+	},
+	&cli.StringFlag{
+		Name:  "since",
+		Usage: "only articles updated on or after the `DATE` like 2020-04-01",
+	},
+	&cli.BoolFlag{
+		Name:  "private-only",
+		Usage: "only private articles",
+	},
+	&cli.BoolFlag{
+		Name:  "public-only",
+		Usage: "only public articles",
+	},
+}
+
+func articleFilter(c *cli.Context) (*qiisync.ArticleFilter, error) {
+	if c.Bool("private-only") && c.Bool("public-only") {
+		return nil, errors.New("--private-only and --public-only cannot be specified at the same time")
+	}
+	f := &qiisync.ArticleFilter{
+		Tags:        c.StringSlice("tag"),
+		PrivateOnly: c.Bool("private-only"),
+		PublicOnly:  c.Bool("public-only"),
+	}
+	if s := c.String("since"); s != "" {
+		since, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("since must be like 2020-04-01: %w", err)
+		}
+		f.Since = since
+	}
+	return f, nil
+}
+
+var commandNew = &cli.Command{
+	Name:      "new",
+	Usage:     "Create a new private Article in base_dir",
+	ArgsUsage: "<title>",
+	Description: `The Article is rendered from the template in ~/.config/qiisync/templates/<name>.md,
+   where {{.Title}} and {{.Date}} are replaced. The header of the template is kept,
+   but the Article is always private until it is posted.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "template",
+			Aliases: []string{"t"},
+			Usage:   "create the Article from the template of `NAME`",
+		},
+		&cli.StringFlag{
+			Name:  "tag",
+			Usage: "tags of the Article like \"React,redux,TypeScript\" or \"Python:3.7\"",
+		},
+		&cli.BoolFlag{
+			Name:  "edit",
+			Usage: "open the Article with $EDITOR",
+		},
+		&cli.BoolFlag{
+			Name:  "list-templates",
+			Usage: "list the templates",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+		b := newBroker(c, conf)
+
+		if c.Bool("list-templates") {
+			names, err := b.Templates()
+			if err != nil {
 				return err
 			}
+			for _, name := range names {
+				fmt.Fprintln(os.Stdout, name)
+			}
+			return nil
+		}
+
+		title := c.Args().First()
+		if title == "" {
+			_ = cli.ShowCommandHelp(c, "new")
+			return errCommandHelp
+		}
+		a, err := b.NewArticle(title, c.String("template"), c.String("tag"))
+		if err != nil {
+			return err
+		}
+		if c.Bool("edit") {
+			return openEditor(a.FilePath)
 		}
 		return nil
 	},
 }
 
+// openEditor opens the file with $VISUAL or $EDITOR, which may have arguments like "code --wait".
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return errors.New("$EDITOR is not set")
+	}
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+var allowSecretsFlag = &cli.BoolFlag{
+	Name:  "allow-secrets",
+	Usage: "post the Article even if it seems to contain secrets",
+}
+
 var commandPost = &cli.Command{
 	Name:  "post",
 	Usage: "Post a new Article to remote",
+	Description: `The title and the tags of the Article are taken from the flags, then from the header
+   of the file. Only the missing ones are asked on the terminal. The visibility is always
+   asked unless --private is specified, since posting publicly cannot be undone.`,
+	Flags: []cli.Flag{
+		allowSecretsFlag,
+		&cli.StringFlag{
+			Name:  "title",
+			Usage: "`TITLE` of the Article",
+		},
+		&cli.StringFlag{
+			Name:  "tag",
+			Usage: "tags of the Article like \"React,redux,TypeScript\" or \"Python:3.7\"",
+		},
+		&cli.BoolFlag{
+			Name:  "private",
+			Usage: "post the Article privately",
+		},
+		&cli.StringFlag{
+			Name:  "organization",
+			Usage: "post the Article under the organization of `URL_NAME`",
+		},
+		&cli.BoolFlag{
+			Name:  "slide",
+			Usage: "show the Article in the slide mode",
+		},
+		&cli.BoolFlag{
+			Name:  "tweet",
+			Usage: "tweet the Article with the linked Twitter account",
+		},
+	},
 	Action: func(c *cli.Context) error {
 		filename := c.Args().First()
 		if filename == "" {
@@ -69,65 +414,89 @@ var commandPost = &cli.Command{
 			return err
 		}
 
-		// Receives parameters from the stdin.
-		sc := bufio.NewScanner(os.Stdin)
+		a, err := qiisync.ArticleFromFile(filename)
+		if err != nil {
+			return err
+		}
+
 		var (
-			title   string
-			tag     string
-			private bool
+			title        = a.Title
+			tag          = a.Tags
+			organization = a.Organization
+			slide        = a.SlideOr(false)
+			tweet        = a.Tweet
+			privateFlag  *bool
 		)
+		if c.IsSet("title") {
+			title = c.String("title")
+		}
+		if c.IsSet("tag") {
+			tag = c.String("tag")
+		}
+		if c.IsSet("private") {
+			v := c.Bool("private")
+			privateFlag = &v
+		}
+		if c.IsSet("organization") {
+			organization = c.String("organization")
+		}
+		if c.IsSet("slide") {
+			slide = c.Bool("slide")
+		}
+		if c.IsSet("tweet") {
+			tweet = c.Bool("tweet")
+		}
+
+		// Receives the missing parameters from the stdin.
+		sc := bufio.NewScanner(os.Stdin)
 
-		fmt.Fprintln(os.Stdout, "")
-		fmt.Fprintln(os.Stdout, `Please enter the "title" of the Article you want to post.`)
-		if sc.Scan() {
-			title = sc.Text()
+		if title == "" {
+			fmt.Fprintln(os.Stdout, "")
+			fmt.Fprintln(os.Stdout, `Please enter the "title" of the Article you want to post.`)
+			if sc.Scan() {
+				title = sc.Text()
+			}
+			if err := sc.Err(); err != nil {
+				return fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
+			}
 			if title == "" {
 				return fmt.Errorf("title is required")
 			}
 		}
-		if err := sc.Err(); err != nil {
-			return fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
-		}
 
-		fmt.Fprintln(os.Stdout, "")
-		fmt.Fprintln(os.Stdout, `Please enter the "tag" of the Article you want to post.`)
-		fmt.Fprintln(os.Stdout, `Tag is like "React,redux,TypeScript" or "Go" or "Python:3.7". To specify more than one, separate them with ",".`)
-		if sc.Scan() {
-			tag = sc.Text()
+		if tag == "" {
+			fmt.Fprintln(os.Stdout, "")
+			fmt.Fprintln(os.Stdout, `Please enter the "tag" of the Article you want to post.`)
+			fmt.Fprintln(os.Stdout, `Tag is like "React,redux,TypeScript" or "Go" or "Python:3.7". To specify more than one, separate them with ",".`)
+			if sc.Scan() {
+				tag = sc.Text()
+			}
+			if err := sc.Err(); err != nil {
+				return fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
+			}
 			if tag == "" {
 				return fmt.Errorf("more than one tag is required")
 			}
 		}
-		if err := sc.Err(); err != nil {
-			return fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
-		}
-
-		fmt.Fprintln(os.Stdout, "")
-		fmt.Fprintln(os.Stdout, `Do you make the Article you post private? "true" is private, "false" is public.`)
-		if sc.Scan() {
-			text := sc.Text()
-			private, err = strconv.ParseBool(text)
-			if err != nil {
-				return fmt.Errorf("input string (%s) could not be parsed into bool", text)
-			}
-		}
-		if err := sc.Err(); err != nil {
-			return fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
-		}
 
-		a, err := qiisync.ArticleFromFile(filename)
+		private, err := postPrivate(privateFlag, sc, os.Stdout)
 		if err != nil {
 			return err
 		}
 
 		post := &qiisync.PostItem{
-			Body:    a.Item.Body,
-			Private: private,
-			Tags:    qiisync.MarshalTag(tag),
-			Title:   title,
+			Body:                a.Item.Body,
+			Private:             private,
+			Tags:                qiisync.MarshalTag(tag),
+			Title:               title,
+			Slide:               slide,
+			OrganizationURLName: organization,
+			Tweet:               tweet,
+			FilePath:            a.FilePath,
 		}
 
-		b := qiisync.NewBroker(conf)
+		b := newBroker(c, conf)
+		b.AllowSecrets = c.Bool("allow-secrets")
 		err = b.PostArticle(post)
 		if err != nil {
 			return err
@@ -136,9 +505,38 @@ var commandPost = &cli.Command{
 	},
 }
 
+// postPrivate returns whether a new Article is posted privately. It is always asked on the terminal
+// unless --private is specified, whatever the header says, since posting publicly cannot be undone.
+func postPrivate(flag *bool, sc *bufio.Scanner, w io.Writer) (bool, error) {
+	if flag != nil {
+		return *flag, nil
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, `Do you make the Article you post private? "true" is private, "false" is public.`)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return false, fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
+		}
+		return false, errors.New("visibility of the Article is required. use --private to specify it")
+	}
+	text := sc.Text()
+	private, err := strconv.ParseBool(text)
+	if err != nil {
+		return false, fmt.Errorf("input string (%s) could not be parsed into bool", text)
+	}
+	return private, nil
+}
+
 var commandUpdate = &cli.Command{
 	Name:  "update",
 	Usage: "Push local Article to remote",
+	Flags: []cli.Flag{
+		allowSecretsFlag,
+		&cli.BoolFlag{
+			Name:  "publish",
+			Usage: "make the private Article public without confirmation",
+		},
+	},
 	Action: func(c *cli.Context) error {
 		filename := c.Args().First()
 		if filename == "" {
@@ -156,7 +554,10 @@ var commandUpdate = &cli.Command{
 			return err
 		}
 
-		b := qiisync.NewBroker(conf)
+		b := newBroker(c, conf)
+		b.AllowSecrets = c.Bool("allow-secrets")
+		b.Publish = c.Bool("publish")
+		b.ConfirmPublish = confirmPublish
 		_, err = b.UploadFresh(a)
 		if err != nil {
 			return err
@@ -164,3 +565,831 @@ var commandUpdate = &cli.Command{
 		return nil
 	},
 }
+
+var commandPush = &cli.Command{
+	Name:      "push",
+	Usage:     "Push local Articles to remote",
+	ArgsUsage: "[<filepath>...]",
+	Description: `All the local Articles are pushed if no file is specified. Articles without ID
+   are never posted.`,
+	Flags: []cli.Flag{
+		allowSecretsFlag,
+		&cli.BoolFlag{
+			Name:  "publish",
+			Usage: "make the private Articles public without confirmation",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only check the Articles, and lint the ones without ID",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only the Articles changed since the git `REF`, or by the commits in a range like A..B",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		b.AllowSecrets = c.Bool("allow-secrets")
+		b.Publish = c.Bool("publish")
+		b.ConfirmPublish = confirmPublish
+		b.DryRun = c.Bool("dry-run")
+
+		files := c.Args().Slice()
+		switch {
+		case len(files) > 0:
+		case c.String("since") != "":
+			files, err = b.ChangedArticleFiles(c.String("since"))
+		default:
+			files, err = b.LocalArticleFiles()
+		}
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, r := range b.PushArticles(files) {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d Articles failed to be pushed", failed, len(files))
+		}
+		return nil
+	},
+}
+
+var commandList = &cli.Command{
+	Name:  "list",
+	Usage: "List local Articles, or Articles on remote with --remote",
+	Flags: append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:  "remote",
+			Usage: "list the Articles on remote instead of base_dir",
+		},
+		&cli.StringFlag{
+			Name:  "sort",
+			Value: "updated_at",
+			Usage: "sort by `KEY`, one of id, title, created_at, updated_at and path",
+		},
+		&cli.BoolFlag{
+			Name:  "asc",
+			Usage: "sort in ascending order",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: "output `FORMAT`, table, csv, json or a Go template like '{{.ID}} {{.Title}}'",
+		},
+	}, filterFlags...),
+	Action: func(c *cli.Context) error {
+		filter, err := articleFilter(c)
+		if err != nil {
+			return err
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		summaries, err := newBroker(c, conf).ListArticles(c.Bool("remote"), filter)
+		if err != nil {
+			return err
+		}
+		if err := qiisync.SortArticleSummaries(summaries, c.String("sort"), c.Bool("asc")); err != nil {
+			return err
+		}
+		return qiisync.WriteArticleSummaries(os.Stdout, summaries, c.String("format"))
+	},
+}
+
+var commandSearch = &cli.Command{
+	Name:      "search",
+	Usage:     "Search local Articles",
+	ArgsUsage: "<query>",
+	Description: `Articles that contain all the words in the query in the title, the tags or the body
+   are shown from the best match. The index is kept in base_dir/.qiisync/search.json.`,
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Value: 20,
+			Usage: "show at most `N` Articles, or all of them if 0",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "output `FORMAT`, text or json",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		query := strings.Join(c.Args().Slice(), " ")
+		if strings.TrimSpace(query) == "" {
+			_ = cli.ShowCommandHelp(c, "search")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		results, err := newBroker(c, conf).Search(query, c.Int("limit"))
+		if err != nil {
+			return err
+		}
+		switch c.String("format") {
+		case "json":
+			if results == nil {
+				results = []*qiisync.SearchResult{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(results)
+		case "text":
+			for _, r := range results {
+				fmt.Fprintf(os.Stdout, "%s\t%s\n    %s\n", r.Path, r.Title, r.Snippet)
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown format: %s", c.String("format"))
+	},
+}
+
+var commandExport = &cli.Command{
+	Name:      "export",
+	Usage:     "Export local Articles for Zenn, Hugo or Jekyll",
+	ArgsUsage: "<dir> [<filepath>...]",
+	Description: `All the local Articles are exported if no file is specified. The Articles are written
+   under <dir> in the layout of the format, such as articles/ of Zenn, content/posts/ of Hugo
+   and _posts/ of Jekyll. Private Articles are exported as drafts.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "export in `FORMAT`, zenn, hugo or jekyll",
+			Required: true,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		dir := c.Args().First()
+		if dir == "" {
+			_ = cli.ShowCommandHelp(c, "export")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		files := c.Args().Tail()
+		if len(files) == 0 {
+			files, err = b.LocalArticleFiles()
+			if err != nil {
+				return err
+			}
+		}
+		_, err = b.Export(c.String("to"), dir, files)
+		return err
+	},
+}
+
+var commandImportDir = &cli.Command{
+	Name:      "import-dir",
+	Usage:     "Import articles of Zenn, Hugo or Markdown as new local Articles",
+	ArgsUsage: "<dir>",
+	Description: `The articles under <dir> are converted to Qiita Markdown and written to base_dir as
+   new Articles, which are posted with "qiisync post". The constructs that could not be
+   converted are reported with their line numbers. Existing files are never overwritten.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "import from `FORMAT`, zenn, hugo or markdown",
+			Required: true,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		dir := c.Args().First()
+		if dir == "" {
+			_ = cli.ShowCommandHelp(c, "import-dir")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		results, err := b.ImportDir(c.String("from"), dir)
+		if err != nil {
+			return err
+		}
+		var failed int
+		for _, r := range results {
+			if r.Err != nil {
+				qiisync.Logf("error", "%s: %v", r.Source, r.Err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s ---> %s\n", r.Source, r.Path)
+			for _, issue := range r.Issues {
+				qiisync.Logf("import", "%s: %s", r.Source, issue)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d files failed to be imported", failed, len(results))
+		}
+		return nil
+	},
+}
+
+var commandImport = &cli.Command{
+	Name:      "import",
+	Usage:     "Import an existing Article from remote as a new local file",
+	ArgsUsage: "<url>",
+	Action: func(c *cli.Context) error {
+		arg := c.Args().First()
+		if arg == "" {
+			_ = cli.ShowCommandHelp(c, "import")
+			return errCommandHelp
+		}
+
+		id, err := qiisync.ParseItemID(arg)
+		if err != nil {
+			return err
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		_, err = b.ImportArticle(id)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+var commandPreview = &cli.Command{
+	Name:      "preview",
+	Usage:     "Preview an Article rendered as Qiita Markdown",
+	ArgsUsage: "<filepath>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "addr",
+			Value: "localhost:8888",
+			Usage: "`ADDRESS` of the preview server",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "preview")
+			return errCommandHelp
+		}
+		if _, err := qiisync.ArticleFromFile(filename); err != nil {
+			return err
+		}
+
+		addr := c.String("addr")
+		qiisync.Logf("preview", "http://%s/ (Ctrl+C to stop)", addr)
+		return http.ListenAndServe(addr, qiisync.NewPreviewHandler(filename))
+	},
+}
+
+var commandRender = &cli.Command{
+	Name:      "render",
+	Usage:     "Print the HTML of an Article rendered by Qiita",
+	ArgsUsage: "<filepath>",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "remote",
+			Usage: "fetch the HTML from remote even if it is stored locally",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "render")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		a, err := qiisync.ArticleFromFile(filename)
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		html, err := b.RenderedHTML(a, c.Bool("remote"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, html)
+		return nil
+	},
+}
+
+var commandLint = &cli.Command{
+	Name:      "lint",
+	Usage:     "Check Articles for problems of Qiita Markdown",
+	ArgsUsage: "[<filepath>...]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "output `FORMAT`, text or json",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			// Files can be linted without the configuration, e.g. on CI.
+			if !errors.Is(err, os.ErrNotExist) || c.NArg() == 0 {
+				return err
+			}
+			conf = &qiisync.Config{}
+		}
+		b := newBroker(c, conf)
+
+		files := c.Args().Slice()
+		if len(files) == 0 {
+			files, err = b.LocalArticleFiles()
+			if err != nil {
+				return err
+			}
+		}
+
+		issues := []*qiisync.LintIssue{}
+		for _, f := range files {
+			a, err := qiisync.ArticleFromFile(f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+			issues = append(issues, b.Lint(a)...)
+		}
+
+		switch c.String("format") {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(issues); err != nil {
+				return err
+			}
+		case "text":
+			for _, i := range issues {
+				fmt.Fprintln(os.Stdout, i)
+			}
+		default:
+			return fmt.Errorf("unknown format: %s", c.String("format"))
+		}
+
+		errs := 0
+		for _, i := range issues {
+			if i.Severity == qiisync.SeverityError {
+				errs++
+			}
+		}
+		if errs > 0 {
+			return fmt.Errorf("%d lint errors found", errs)
+		}
+		return nil
+	},
+}
+
+var commandTags = &cli.Command{
+	Name:  "tags",
+	Usage: "List tags used in local Articles",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "look up the tags on Qiita and show the number of followers",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		counts, err := b.LocalTagCounts()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		if !c.Bool("check") {
+			fmt.Fprintln(tw, "COUNT\tTAG")
+			for _, tc := range counts {
+				fmt.Fprintf(tw, "%d\t%s\n", tc.Count, tc.Name)
+			}
+			return tw.Flush()
+		}
+
+		names := make([]string, len(counts))
+		for i, tc := range counts {
+			names[i] = tc.Name
+		}
+		tags, err := b.LookupTags(names)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(tw, "COUNT\tTAG\tFOLLOWERS\tITEMS\tNOTE")
+		for _, tc := range counts {
+			tag := tags[tc.Name]
+			switch {
+			case tag == nil:
+				fmt.Fprintf(tw, "%d\t%s\t-\t-\tnot found on Qiita\n", tc.Count, tc.Name)
+			case tag.ID != tc.Name:
+				fmt.Fprintf(tw, "%d\t%s\t%d\t%d\tspelled %s on Qiita\n", tc.Count, tc.Name, tag.FollowersCount, tag.ItemsCount, tag.ID)
+			default:
+				fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t\n", tc.Count, tc.Name, tag.FollowersCount, tag.ItemsCount)
+			}
+		}
+		return tw.Flush()
+	},
+}
+
+var commandPublishDue = &cli.Command{
+	Name:  "publish-due",
+	Usage: "Publish local Articles whose PublishAt has passed",
+	Description: `New Articles are posted publicly and private Articles on remote are made public
+   without confirmation. It is intended to be run periodically, e.g. from cron.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only show the Articles to be published",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		if c.Bool("dry-run") {
+			due, err := b.DueArticles()
+			if err != nil {
+				return err
+			}
+			for _, a := range due {
+				fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", a.PublishAt, a.FilePath, a.Title)
+			}
+			return nil
+		}
+
+		results, err := b.PublishDue()
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d Articles failed to be published", failed, len(results))
+		}
+		return nil
+	},
+}
+
+var commandRestore = &cli.Command{
+	Name:      "restore",
+	Usage:     "Restore local Article from the backup",
+	ArgsUsage: "<filepath>",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "list the backups from newest to oldest",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "restore the backup of `NAME` like 20200501120000 instead of the newest one",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "restore")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		if c.Bool("list") {
+			backups, err := b.Backups(filename)
+			if err != nil {
+				return err
+			}
+			for _, p := range backups {
+				fmt.Fprintln(os.Stdout, p)
+			}
+			return nil
+		}
+
+		_, err = b.Restore(filename, c.String("from"))
+		return err
+	},
+}
+
+var commandLog = &cli.Command{
+	Name:      "log",
+	Usage:     "Show the sync history of an Article",
+	ArgsUsage: "<filepath>",
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "log")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		a, err := qiisync.ArticleFromFile(filename)
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		revs, err := b.History(a.ID)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "REV\tACTION\tSYNCED AT\tUPDATED AT\tTITLE")
+		for i := len(revs) - 1; i >= 0; i-- {
+			r := revs[i]
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.Rev, r.Action,
+				r.SyncedAt.Local().Format("2006-01-02 15:04:05"), r.UpdatedAt.Local().Format("2006-01-02 15:04:05"), r.Title)
+		}
+		return tw.Flush()
+	},
+}
+
+var commandShow = &cli.Command{
+	Name:      "show",
+	Usage:     "Print a revision of an Article in the sync history",
+	ArgsUsage: "<filepath>",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "rev",
+			Usage: "revision `N` shown by the log command, the newest one by default",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "show")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		a, err := qiisync.ArticleFromFile(filename)
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		r, err := b.Revision(a.ID, c.Int("rev"))
+		if err != nil {
+			return err
+		}
+		content, err := r.Content(a.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, content)
+		return nil
+	},
+}
+
+// confirmPublish asks the user on the terminal whether a private Article is made public.
+func confirmPublish(title, url string) (bool, error) {
+	fmt.Fprintln(os.Stdout, "")
+	fmt.Fprintf(os.Stdout, "%q is private.\n%s\n", title, url)
+	fmt.Fprintln(os.Stdout, `Do you really make the Article public? It cannot be undone. Enter "yes" to publish.`)
+	sc := bufio.NewScanner(os.Stdin)
+	if sc.Scan() {
+		return sc.Text() == "yes", nil
+	}
+	if err := sc.Err(); err != nil {
+		return false, fmt.Errorf("an unexpected error has occurred when scanning: %w", err)
+	}
+	return false, nil
+}
+
+var commandWatch = &cli.Command{
+	Name:  "watch",
+	Usage: "Push local Articles to Qiita when they are saved",
+	Description: `Articles are pushed with the same checks as update. Articles without ID are
+   never posted, and private Articles are never made public. Stop with Ctrl-C.`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "debounce",
+			Usage: "push after the Article is left unchanged for `DURATION`",
+			Value: 2 * time.Second,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		w := newBroker(c, conf).NewWatcher()
+		w.Debounce = c.Duration("debounce")
+
+		stop := make(chan struct{})
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			<-sig
+			close(stop)
+		}()
+		return w.Run(stop)
+	},
+}
+
+var commandHook = &cli.Command{
+	Name:  "hook",
+	Usage: "Manage the git hooks of the repository of base_dir",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "install",
+			Usage: "Install the pre-push hook that runs push --dry-run",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "overwrite the pre-push hook not installed by qiisync",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				conf, err := qiisync.LoadConfiguration()
+				if err != nil {
+					return err
+				}
+
+				p, err := newBroker(c, conf).InstallPrePushHook(c.Bool("force"))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "installed %s\n", p)
+				return nil
+			},
+		},
+	},
+}
+
+var commandComments = &cli.Command{
+	Name:      "comments",
+	Usage:     "List comments on an Article",
+	ArgsUsage: "<filepath>",
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "comments")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		a, err := qiisync.ArticleFromFile(filename)
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		comments, err := b.FetchComments(a.ID)
+		if err != nil {
+			return err
+		}
+		for _, cm := range comments {
+			fmt.Fprintf(os.Stdout, "@%s (%s) %s\n", cm.User.ID, cm.CreatedAt.Local().Format("2006-01-02 15:04"), cm.ID)
+			fmt.Fprintln(os.Stdout, cm.Body)
+			fmt.Fprintln(os.Stdout, "")
+		}
+		return nil
+	},
+}
+
+var commandComment = &cli.Command{
+	Name:      "comment",
+	Usage:     "Post a comment on an Article",
+	ArgsUsage: "<filepath>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "message",
+			Aliases:  []string{"m"},
+			Usage:    "the `BODY` of the comment in Markdown",
+			Required: true,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		filename := c.Args().First()
+		if filename == "" {
+			_ = cli.ShowCommandHelp(c, "comment")
+			return errCommandHelp
+		}
+
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		a, err := qiisync.ArticleFromFile(filename)
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		_, err = b.PostComment(a.ID, c.String("message"))
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+var commandStats = &cli.Command{
+	Name:  "stats",
+	Usage: "Show statistics of Articles on remote",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "sort",
+			Value: "likes",
+			Usage: "sort by `KEY`, one of title, created_at, likes, stocks, reactions, comments and page_views",
+		},
+		&cli.BoolFlag{
+			Name:  "asc",
+			Usage: "sort in ascending order",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: "output `FORMAT`, table, csv or json",
+		},
+		&cli.BoolFlag{
+			Name:  "history",
+			Usage: "append the statistics of today to the history file",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		conf, err := qiisync.LoadConfiguration()
+		if err != nil {
+			return err
+		}
+
+		b := newBroker(c, conf)
+		stats, err := b.FetchStats()
+		if err != nil {
+			return err
+		}
+		if err := qiisync.SortStats(stats, c.String("sort"), c.Bool("asc")); err != nil {
+			return err
+		}
+		if err := qiisync.WriteStats(os.Stdout, stats, c.String("format")); err != nil {
+			return err
+		}
+
+		if c.Bool("history") {
+			p, err := b.AppendStatsHistory(stats)
+			if err != nil {
+				return err
+			}
+			qiisync.Logf("store", "%s", p)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func Test_postPrivate(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		flag    *bool
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{
+			// A header without Private, or with "Private: false", is asked as well,
+			// since the visibility in the header is not a confirmation to post publicly.
+			name:  "no_flag",
+			input: "true\n",
+			want:  true,
+		},
+		{
+			name:  "no_flag_public",
+			input: "false\n",
+			want:  false,
+		},
+		{
+			name:    "no_answer",
+			wantErr: true,
+		},
+		{
+			name:    "invalid_answer",
+			input:   "maybe\n",
+			wantErr: true,
+		},
+		{
+			name: "flag_private",
+			flag: &yes,
+			want: true,
+		},
+		{
+			name: "flag_public",
+			flag: &no,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, err := postPrivate(tt.flag, bufio.NewScanner(strings.NewReader(tt.input)), &out)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("postPrivate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("postPrivate() = %v, want %v", got, tt.want)
+			}
+			if asked := out.Len() > 0; asked != (tt.flag == nil) {
+				t.Errorf("postPrivate() asked = %v, want %v", asked, tt.flag == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package qiisync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const commentsExtension = ".comments.yaml"
+
+// commentRecord is a comment stored in the sidecar file of an article.
+type commentRecord struct {
+	ID        string    `yaml:"ID"`
+	User      string    `yaml:"User"`
+	CreatedAt time.Time `yaml:"CreatedAt"`
+	UpdatedAt time.Time `yaml:"UpdatedAt"`
+	Body      string    `yaml:"Body"`
+}
+
+// FetchComments extracts the comments on the article from Qiita in the order they were posted.
+func (b *Broker) FetchComments(id string) ([]*Comment, error) {
+	if id == "" {
+		return nil, errors.New("article ID is required")
+	}
+	u := fmt.Sprintf("api/v2/items/%s/comments", id)
+	req, err := b.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var comments []*Comment
+	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+		return nil, err
+	}
+	// Qiita returns the newest comment first.
+	for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+		comments[i], comments[j] = comments[j], comments[i]
+	}
+	return comments, nil
+}
+
+// PostComment posts a comment on the article to Qiita.
+func (b *Broker) PostComment(id, body string) (*Comment, error) {
+	if id == "" {
+		return nil, errors.New("article ID is required")
+	}
+	u := fmt.Sprintf("api/v2/items/%s/comments", id)
+	req, err := b.NewRequest(http.MethodPost, u, struct {
+		Body string `json:"body"`
+	}{Body: body})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError(resp)
+	}
+
+	var c Comment
+	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	Logf("post", "comment ---> %s", id)
+	return &c, nil
+}
+
+func commentsPath(articlePath string) string {
+	return strings.TrimSuffix(articlePath, filepath.Ext(articlePath)) + commentsExtension
+}
+
+// StoreComments stores the comments on the remote article in the sidecar file
+// next to the local file of the article. The sidecar file is removed if all the
+// comments have been deleted on Qiita.
+func (b *Broker) StoreComments(localArticles map[string]*Article, remoteArticle *Article) error {
+	comments, err := b.FetchComments(remoteArticle.ID)
+	if err != nil {
+		return err
+	}
+	p := commentsPath(b.freshPath(localArticles, remoteArticle))
+	if len(comments) == 0 {
+		if err := os.Remove(p); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		Logf("store", "remove %s", p)
+		return nil
+	}
+
+	records := make([]*commentRecord, len(comments))
+	for i, c := range comments {
+		records[i] = &commentRecord{
+			ID:        c.ID,
+			User:      c.User.ID,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Body:      c.Body,
+		}
+	}
+	d, err := yaml.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	Logf("store", "%s", p)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(p, d, 0644)
+}
@@ -0,0 +1,135 @@
+package qiisync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreComments(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	deleted := false
+	mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d/comments", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if deleted {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `
+					[
+						{
+							"body": "ありがとうございます",
+							"created_at": "2000-01-02T00:00:00+00:00",
+							"id": "3391f50c35f953abfc4f",
+							"rendered_body": "<p>ありがとうございます</p>",
+							"updated_at": "2000-01-02T00:00:00+00:00",
+							"user": {"id": "d-tsuji", "name": "d-tsuji"}
+						},
+						{
+							"body": "質問です",
+							"created_at": "2000-01-01T00:00:00+00:00",
+							"id": "1111f50c35f953abfc4f",
+							"rendered_body": "<p>質問です</p>",
+							"updated_at": "2000-01-01T00:00:00+00:00",
+							"user": {"id": "qiita", "name": "Qiita キータ"}
+						}
+					]
+`)
+	})
+
+	remote := &Article{
+		ArticleHeader: &ArticleHeader{ID: "c686397e4a0f4f11683d", Title: "Example title"},
+		Item: &Item{
+			ID:        "c686397e4a0f4f11683d",
+			Title:     "Example title",
+			CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	if err := b.StoreComments(map[string]*Article{}, remote); err != nil {
+		t.Errorf("StoreComments(): %v", err)
+		return
+	}
+
+	p := filepath.Join(b.baseDir(), "20000101", "Example title.comments.yaml")
+	fbyte, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Errorf("read file: %s, %v", p, err)
+		return
+	}
+	want := `- ID: 1111f50c35f953abfc4f
+  User: qiita
+  CreatedAt: 2000-01-01T00:00:00Z
+  UpdatedAt: 2000-01-01T00:00:00Z
+  Body: 質問です
+- ID: 3391f50c35f953abfc4f
+  User: d-tsuji
+  CreatedAt: 2000-01-02T00:00:00Z
+  UpdatedAt: 2000-01-02T00:00:00Z
+  Body: ありがとうございます
+`
+	if got := string(fbyte); got != want {
+		t.Errorf("Stored comments: %v, want %v", got, want)
+	}
+
+	// The comments deleted on Qiita are removed from the local copy as well.
+	deleted = true
+	for i := 0; i < 2; i++ {
+		if err := b.StoreComments(map[string]*Article{}, remote); err != nil {
+			t.Errorf("StoreComments(): %v", err)
+			return
+		}
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("comments deleted on Qiita are left in %s: %v", p, err)
+		}
+	}
+}
+
+func TestPostComment(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() { teardown() })
+
+	mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d/comments", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		var body struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("json decode: %v", err)
+		}
+		if body.Body != "どういたしまして" {
+			t.Errorf("comment body: %v, want %v", body.Body, "どういたしまして")
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"body": "どういたしまして", "id": "4444f50c35f953abfc4f", "user": {"id": "d-tsuji"}}`)
+	})
+
+	got, err := b.PostComment("c686397e4a0f4f11683d", "どういたしまして")
+	if err != nil {
+		t.Errorf("PostComment(): %v", err)
+		return
+	}
+	if got.ID != "4444f50c35f953abfc4f" {
+		t.Errorf("PostComment() ID = %v, want %v", got.ID, "4444f50c35f953abfc4f")
+	}
+}
+
+func TestPostCommentNoID(t *testing.T) {
+	b, _, _, teardown := setup()
+	defer teardown()
+
+	if _, err := b.PostComment("", "body"); err == nil {
+		t.Errorf("expected error occurred if no article ID")
+	}
+}
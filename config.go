@@ -4,14 +4,21 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
 // Config stores Qiita's configuration and local environment settings.
 type Config struct {
-	Qiita qiitaConfig `toml:"qiita"`
-	Local localConfig `toml:"local"`
+	Qiita    qiitaConfig    `toml:"qiita"`
+	Local    localConfig    `toml:"local"`
+	Uploader uploaderConfig `toml:"uploader"`
+	Lint     lintConfig     `toml:"lint"`
+	Secrets  secretsConfig  `toml:"secrets"`
+	Tags     tagsConfig     `toml:"tags"`
+	Git      gitConfig      `toml:"git"`
+	Export   exportConfig   `toml:"export"`
 }
 
 type qiitaConfig struct {
@@ -19,17 +26,90 @@ type qiitaConfig struct {
 }
 
 type localConfig struct {
-	Dir          string `toml:"base_dir"`
-	FileNameMode string `toml:"filename_mode"`
+	Dir               string `toml:"base_dir"`
+	FileNameMode      string `toml:"filename_mode"`
+	DownloadImages    bool   `toml:"download_images"`
+	RewriteImageLinks bool   `toml:"rewrite_image_links"`
+	SaveHTML          bool   `toml:"save_html"`
+	HTMLDir           string `toml:"html_dir"`
+	PullComments      bool   `toml:"pull_comments"`
+
+	// Extensions are the extensions of the files treated as articles.
+	Extensions        []string `toml:"extensions"`
+	IncludeHiddenDirs bool     `toml:"include_hidden_dirs"`
+	// BackupDir is where the previous versions of overwritten articles are kept.
+	BackupDir string `toml:"backup_dir"`
+	// HistoryLimit is the number of the revisions kept in the history per article.
+	// It defaults to 100, and a negative value keeps all the revisions.
+	HistoryLimit int `toml:"history_limit"`
+	// TemplateDir is where the templates of new articles are. It defaults to
+	// the templates directory in the directory of the configuration file.
+	TemplateDir string `toml:"template_dir"`
+}
+
+// uploaderConfig specifies how local images in articles are uploaded.
+// Either command or put_url is used.
+type uploaderConfig struct {
+	Command   string `toml:"command"`
+	PutURL    string `toml:"put_url"`
+	PublicURL string `toml:"public_url"`
+}
+
+// lintConfig configures the linter.
+// Rules maps the name of a rule to its severity, "error", "warning" or "off".
+type lintConfig struct {
+	Rules          map[string]string `toml:"rules"`
+	MaxTitleLength int               `toml:"max_title_length"`
+}
+
+// secretsConfig configures the scanner of sensitive data.
+type secretsConfig struct {
+	// EmailDomains are the domains of email addresses that must not be published, such as "company.example".
+	EmailDomains []string           `toml:"email_domains"`
+	Disable      []string           `toml:"disable"`
+	Rules        []secretRuleConfig `toml:"rules"`
+}
+
+type secretRuleConfig struct {
+	Name    string `toml:"name"`
+	Pattern string `toml:"pattern"`
+}
+
+// tagsConfig configures the check of the tags of articles against Qiita.
+type tagsConfig struct {
+	SkipCheck    bool `toml:"skip_check"`
+	MinFollowers int  `toml:"min_followers"`
+}
+
+// gitConfig configures the integration with the git repository of base_dir.
+// Commit is the mode of committing the articles stored by pull, "run" or "article".
+type gitConfig struct {
+	Commit string `toml:"commit"`
+}
+
+// configDir returns the directory of the configuration file, "~/.config/qiisync".
+func configDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "qiisync"), nil
+}
+
+// exportConfig configures the front matter of the exported articles.
+type exportConfig struct {
+	ZennEmoji    string `toml:"zenn_emoji"`
+	ZennType     string `toml:"zenn_type"`
+	JekyllLayout string `toml:"jekyll_layout"`
 }
 
 // LoadConfiguration gets its configuration from "~/.config/qiisync/config".
 func LoadConfiguration() (*Config, error) {
-	home, err := os.UserHomeDir()
+	dir, err := configDir()
 	if err != nil {
 		return nil, err
 	}
-	filename := filepath.Join(home, ".config", "qiisync", "config")
+	filename := filepath.Join(dir, "config")
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -43,9 +123,35 @@ func loadConfig(r io.Reader) (*Config, error) {
 	if _, err := toml.DecodeReader(r, &config); err != nil {
 		return nil, err
 	}
+	if err := config.Lint.validate(); err != nil {
+		return nil, err
+	}
 	return &config, nil
 }
 
 func (c *Config) baseDir() string {
 	return c.Local.Dir
 }
+
+// downloadImages reports whether images in articles are downloaded on pull.
+// Rewriting the links of images requires the images to be downloaded.
+func (c *Config) downloadImages() bool {
+	return c.Local.DownloadImages || c.Local.RewriteImageLinks
+}
+
+// articleExtensions returns the set of the lower-cased extensions of articles.
+func (c *Config) articleExtensions() map[string]bool {
+	exts := c.Local.Extensions
+	if len(exts) == 0 {
+		exts = defaultArticleExtensions
+	}
+	m := make(map[string]bool, len(exts))
+	for _, e := range exts {
+		e = strings.ToLower(e)
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		m[e] = true
+	}
+	return m
+}
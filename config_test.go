@@ -115,6 +115,38 @@ filename_mode = "title"`),
 			},
 			wantErr: false,
 		},
+		{
+			name: "lint_rules",
+			args: args{
+				r: strings.NewReader(`[local]
+base_dir = "./testdata/qiita"
+
+[lint.rules]
+heading-level = "off"
+relative-link = "warning"`),
+			},
+			want: &Config{
+				Local: localConfig{Dir: "./testdata/qiita"},
+				Lint:  lintConfig{Rules: map[string]string{"heading-level": "off", "relative-link": "warning"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown_lint_rule",
+			args: args{
+				r: strings.NewReader(`[lint.rules]
+heading-levels = "off"`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_lint_severity",
+			args: args{
+				r: strings.NewReader(`[lint.rules]
+heading-level = "warn"`),
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid_linux_relative_title",
 			args: args{
@@ -0,0 +1,301 @@
+package qiisync
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The formats of the blogs the local articles are exported to.
+const (
+	ExportZenn   = "zenn"
+	ExportHugo   = "hugo"
+	ExportJekyll = "jekyll"
+)
+
+const (
+	defaultZennEmoji    = "📝"
+	defaultZennType     = "tech"
+	defaultJekyllLayout = "post"
+
+	// maxZennTopics is the number of topics Zenn allows for an article.
+	maxZennTopics = 5
+)
+
+// noteLabels are the labels of the blockquotes that :::note blocks are converted to.
+var noteLabels = map[string]string{"info": "Note", "warn": "Warning", "alert": "Alert"}
+
+// frontMatterField is a field of the front matter of an exported article.
+type frontMatterField struct {
+	key   string
+	value interface{}
+}
+
+// frontMatter writes the fields in order. The values are written in JSON, which is valid YAML as well,
+// so that the emojis and Japanese are kept as they are and the lists are in the flow style like Zenn's.
+func frontMatter(fields []frontMatterField) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	for _, f := range fields {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(f.value); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "%s: %s", f.key, buf.String())
+	}
+	sb.WriteString("---\n")
+	return sb.String(), nil
+}
+
+// Export converts the local articles in files to the format of to, "zenn", "hugo" or "jekyll",
+// and writes them under dir in the directory layout the format expects. It returns the paths of
+// the written files. The private articles and the articles that have not been posted yet are
+// exported as drafts.
+func (b *Broker) Export(to, dir string, files []string) ([]string, error) {
+	switch to {
+	case ExportZenn, ExportHugo, ExportJekyll:
+	default:
+		return nil, fmt.Errorf("unknown export format: %s. use %s, %s or %s", to, ExportZenn, ExportHugo, ExportJekyll)
+	}
+
+	var written []string
+	for _, f := range files {
+		a, err := ArticleFromFile(f)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", f, err)
+		}
+		p, content, err := b.exportArticle(to, dir, a)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", f, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return written, err
+		}
+		if err := writeFileAtomic(p, []byte(content), 0644); err != nil {
+			return written, err
+		}
+		Logf("store", "%s ---> %s", f, p)
+		written = append(written, p)
+	}
+	return written, nil
+}
+
+// exportArticle returns the path under dir and the content of the article in the format of to.
+func (b *Broker) exportArticle(to, dir string, a *Article) (string, string, error) {
+	slug := b.exportSlug(a)
+	draft := a.Private || a.ID == ""
+	created := localCreatedAt(a.FilePath)
+	if created.IsZero() {
+		created = a.Item.UpdatedAt
+	}
+
+	var (
+		p      string
+		fields []frontMatterField
+	)
+	switch to {
+	case ExportZenn:
+		p = filepath.Join(dir, "articles", slug+defaultExtension)
+		fields = []frontMatterField{
+			{"title", a.Title},
+			{"emoji", orDefault(b.Config.Export.ZennEmoji, defaultZennEmoji)},
+			{"type", orDefault(b.Config.Export.ZennType, defaultZennType)},
+			{"topics", zennTopics(a.Tags)},
+			{"published", !draft},
+		}
+	case ExportHugo:
+		p = filepath.Join(dir, "content", "posts", slug+defaultExtension)
+		fields = []frontMatterField{
+			{"title", a.Title},
+			{"date", created.Format(time.RFC3339)},
+			{"lastmod", a.Item.UpdatedAt.Format(time.RFC3339)},
+			{"tags", exportTags(a.Tags)},
+			{"draft", draft},
+		}
+	case ExportJekyll:
+		if draft {
+			p = filepath.Join(dir, "_drafts", slug+defaultExtension)
+		} else {
+			p = filepath.Join(dir, "_posts", created.Format("2006-01-02")+"-"+slug+defaultExtension)
+		}
+		fields = []frontMatterField{
+			{"layout", orDefault(b.Config.Export.JekyllLayout, defaultJekyllLayout)},
+			{"title", a.Title},
+			{"date", created.Format("2006-01-02 15:04:05 -0700")},
+			{"tags", exportTags(a.Tags)},
+		}
+	}
+
+	header, err := frontMatter(fields)
+	if err != nil {
+		return "", "", err
+	}
+	// The links to the images downloaded by pull are relative to base_dir, so they are
+	// restored to the URLs on Qiita to be shown in the exported article.
+	body, err := b.restoreImageLinks(a)
+	if err != nil {
+		return "", "", err
+	}
+	content := header + "\n" + convertMarkdown(body, to)
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return p, content, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// exportSlug returns the name of the exported file. It is the ID of the article, which is
+// lower-case alphanumerics of 20 characters that Zenn accepts as a slug. The article that has
+// not been posted yet is named after the hash of its path, so that it is exported to the same file.
+func (b *Broker) exportSlug(a *Article) string {
+	if a.ID != "" {
+		return strings.ToLower(a.ID)
+	}
+	rel, err := relSlash(b.baseDir(), a.FilePath)
+	if err != nil {
+		rel = a.FilePath
+	}
+	sum := sha1.Sum([]byte(rel))
+	return hex.EncodeToString(sum[:])[:20]
+}
+
+// exportTags returns the names of the tags without their versions.
+func exportTags(tagString string) []string {
+	tags := []string{}
+	if strings.TrimSpace(tagString) == "" {
+		return tags
+	}
+	for _, t := range MarshalTag(tagString) {
+		if name := strings.TrimSpace(t.Name); name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// zennTopics converts the tags to the topics of Zenn, which consist only of lower-case letters and digits.
+func zennTopics(tagString string) []string {
+	topics := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range exportTags(tagString) {
+		topic := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, tag)
+		if topic == "" || seen[topic] {
+			continue
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+		if len(topics) == maxZennTopics {
+			break
+		}
+	}
+	return topics
+}
+
+// convertMarkdown translates the notations of Qiita in body to the dialect of to.
+// Zenn supports "lang:filename" and "$" math as they are, and ":::note" is converted to ":::message".
+// For Hugo and Jekyll, ":::note" blocks are converted to blockquotes with the label, and the file name
+// of a code block is shown above it. Jekyll's kramdown takes "$$" for inline math as well.
+// Code blocks with "math" are converted to "$$" blocks for all of them.
+func convertMarkdown(body, to string) string {
+	var (
+		lines      []string
+		fence      codeFence
+		mathFence  bool
+		inMath     bool
+		inNote     bool
+		mathMarker = "$$"
+	)
+	emit := func(line string) {
+		if inNote {
+			line = strings.TrimRight("> "+line, " ")
+		}
+		lines = append(lines, line)
+	}
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence.inside():
+			if fence.closes(line) && mathFence {
+				mathFence = false
+				line = mathMarker
+			}
+			emit(line)
+		case inMath:
+			if strings.HasSuffix(trimmed, "$$") {
+				inMath = false
+			}
+			emit(line)
+		case fence.opens(line):
+			info := fence.info(line)
+			lang, filename := info, ""
+			if i := strings.Index(info, ":"); i >= 0 {
+				lang, filename = info[:i], info[i+1:]
+			}
+			switch {
+			case lang == "math":
+				mathFence = true
+				emit(mathMarker)
+			case filename != "" && to != ExportZenn:
+				emit("**" + filename + "**")
+				emit("")
+				emit(fence.marker + lang)
+			default:
+				emit(line)
+			}
+		case strings.HasPrefix(trimmed, "$$"):
+			if len(trimmed) < 4 || !strings.HasSuffix(trimmed, "$$") {
+				inMath = true
+			}
+			emit(line)
+		case noteStartReg.MatchString(trimmed):
+			typ := noteStartReg.FindStringSubmatch(trimmed)[1]
+			if !noteTypes[typ] {
+				typ = "info"
+			}
+			if to == ExportZenn {
+				if typ == "info" {
+					emit(":::message")
+				} else {
+					emit(":::message alert")
+				}
+				break
+			}
+			inNote = true
+			emit("**" + noteLabels[typ] + "**")
+			emit("")
+		case trimmed == ":::":
+			if to == ExportZenn || !inNote {
+				emit(line)
+				break
+			}
+			inNote = false
+			emit("")
+		default:
+			if to == ExportJekyll {
+				line = replaceInlineMath(line, func(expr string) string { return "$" + expr + "$" })
+			}
+			emit(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,195 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_convertMarkdown(t *testing.T) {
+	body := "# Title\n\n:::note warn\nBe careful with $x$.\n:::\n\n```go:main.go\n// $a$ and :::note\nfunc main() {}\n```\n\n```math\ne^{i\\pi} = -1\n```\n\n$$\n$y$\n$$\n\nInline $x^2$ and `$code$`.\n"
+	tests := []struct {
+		name string
+		to   string
+		want string
+	}{
+		{
+			name: "zenn",
+			to:   ExportZenn,
+			want: "# Title\n\n:::message alert\nBe careful with $x$.\n:::\n\n```go:main.go\n// $a$ and :::note\nfunc main() {}\n```\n\n$$\ne^{i\\pi} = -1\n$$\n\n$$\n$y$\n$$\n\nInline $x^2$ and `$code$`.\n",
+		},
+		{
+			name: "hugo",
+			to:   ExportHugo,
+			want: "# Title\n\n> **Warning**\n>\n> Be careful with $x$.\n\n\n**main.go**\n\n```go\n// $a$ and :::note\nfunc main() {}\n```\n\n$$\ne^{i\\pi} = -1\n$$\n\n$$\n$y$\n$$\n\nInline $x^2$ and `$code$`.\n",
+		},
+		{
+			name: "jekyll",
+			to:   ExportJekyll,
+			want: "# Title\n\n> **Warning**\n>\n> Be careful with $$x$$.\n\n\n**main.go**\n\n```go\n// $a$ and :::note\nfunc main() {}\n```\n\n$$\ne^{i\\pi} = -1\n$$\n\n$$\n$y$\n$$\n\nInline $$x^2$$ and `$code$`.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, convertMarkdown(body, tt.to)); diff != "" {
+				t.Errorf("convertMarkdown() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_frontMatter(t *testing.T) {
+	got, err := frontMatter([]frontMatterField{
+		{"title", `"Go" & <Rust>`},
+		{"emoji", "🐹"},
+		{"topics", []string{"go", "初心者"}},
+		{"published", true},
+	})
+	if err != nil {
+		t.Errorf("frontMatter(): %v", err)
+		return
+	}
+	want := "---\ntitle: \"\\\"Go\\\" & <Rust>\"\nemoji: \"🐹\"\ntopics: [\"go\",\"初心者\"]\npublished: true\n---\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("frontMatter() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The front matter is valid YAML.
+	var m map[string]interface{}
+	if err := yaml.Unmarshal([]byte(strings.Trim(got, "-\n")), &m); err != nil {
+		t.Errorf("parse front matter: %v", err)
+		return
+	}
+	wantMap := map[string]interface{}{
+		"title":     `"Go" & <Rust>`,
+		"emoji":     "🐹",
+		"topics":    []interface{}{"go", "初心者"},
+		"published": true,
+	}
+	if diff := cmp.Diff(wantMap, m); diff != "" {
+		t.Errorf("parsed front matter mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_zennTopics(t *testing.T) {
+	got := zennTopics("Go:1.14,Next.js,C#,go,初心者,Python,Rust,TypeScript")
+	want := []string{"go", "nextjs", "c", "初心者", "python"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("zennTopics() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExport(t *testing.T) {
+	b, _, _, teardown := setup()
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	write := func(name, content string) string {
+		p := filepath.Join(b.baseDir(), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		updatedAt := time.Date(2020, 4, 24, 9, 0, 0, 0, time.Local)
+		if err := os.Chtimes(p, updatedAt, updatedAt); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	files := []string{
+		write("20200423/go.md", "---\nID: C686397E4A0F4F11683D\nTitle: はじめてのGo\nTags: Go:1.14,Next.js\nPrivate: false\n---\n# Go\n![gopher](images/C686397E4A0F4F11683D/gopher.png)\n"),
+		write("drafts/draft.md", "---\nTitle: 下書き\nTags: Rust\n---\n# Draft\n"),
+	}
+	// The image has been downloaded by pull.
+	imageURL := "https://qiita-image-store.s3.amazonaws.com/0/1/gopher.png"
+	if err := b.saveImageMapping("C686397E4A0F4F11683D", map[string]string{"images/C686397E4A0F4F11683D/gopher.png": imageURL}); err != nil {
+		t.Fatal(err)
+	}
+	draftSlug := b.exportSlug(&Article{ArticleHeader: &ArticleHeader{}, FilePath: files[1]})
+	date := time.Date(2020, 4, 23, 0, 0, 0, 0, time.Local)
+	lastmod := time.Date(2020, 4, 24, 9, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name  string
+		to    string
+		setup func()
+		want  map[string]string
+	}{
+		{
+			name: "zenn",
+			to:   ExportZenn,
+			setup: func() {
+				b.Config.Export.ZennEmoji = "🐹"
+			},
+			want: map[string]string{
+				"articles/c686397e4a0f4f11683d.md": "---\ntitle: \"はじめてのGo\"\nemoji: \"🐹\"\ntype: \"tech\"\ntopics: [\"go\",\"nextjs\"]\npublished: true\n---\n\n# Go\n![gopher](" + imageURL + ")\n",
+				"articles/" + draftSlug + ".md":    "---\ntitle: \"下書き\"\nemoji: \"🐹\"\ntype: \"tech\"\ntopics: [\"rust\"]\npublished: false\n---\n\n# Draft\n",
+			},
+		},
+		{
+			name: "hugo",
+			to:   ExportHugo,
+			want: map[string]string{
+				"content/posts/c686397e4a0f4f11683d.md": "---\ntitle: \"はじめてのGo\"\ndate: \"" + date.Format(time.RFC3339) + "\"\nlastmod: \"" + lastmod.Format(time.RFC3339) + "\"\ntags: [\"Go\",\"Next.js\"]\ndraft: false\n---\n\n# Go\n![gopher](" + imageURL + ")\n",
+				"content/posts/" + draftSlug + ".md":    "---\ntitle: \"下書き\"\ndate: \"" + lastmod.Format(time.RFC3339) + "\"\nlastmod: \"" + lastmod.Format(time.RFC3339) + "\"\ntags: [\"Rust\"]\ndraft: true\n---\n\n# Draft\n",
+			},
+		},
+		{
+			name: "jekyll",
+			to:   ExportJekyll,
+			want: map[string]string{
+				"_posts/2020-04-23-c686397e4a0f4f11683d.md": "---\nlayout: \"post\"\ntitle: \"はじめてのGo\"\ndate: \"" + date.Format("2006-01-02 15:04:05 -0700") + "\"\ntags: [\"Go\",\"Next.js\"]\n---\n\n# Go\n![gopher](" + imageURL + ")\n",
+				"_drafts/" + draftSlug + ".md":              "---\nlayout: \"post\"\ntitle: \"下書き\"\ndate: \"" + lastmod.Format("2006-01-02 15:04:05 -0700") + "\"\ntags: [\"Rust\"]\n---\n\n# Draft\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			dir := filepath.Join(tempDir, tt.name)
+			written, err := b.Export(tt.to, dir, files)
+			if err != nil {
+				t.Errorf("Export(): %v", err)
+				return
+			}
+			got := make(map[string]string)
+			for _, p := range written {
+				d, err := ioutil.ReadFile(p)
+				if err != nil {
+					t.Errorf("read file: %v", err)
+					return
+				}
+				rel, _ := relSlash(dir, p)
+				got[rel] = string(d)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Export() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := b.Export("medium", tempDir, files); err == nil {
+		t.Errorf("Export() succeeded with an unknown format")
+	}
+}
@@ -0,0 +1,56 @@
+package qiisync
+
+import (
+	"strings"
+	"time"
+)
+
+// ArticleFilter narrows down articles to be handled.
+// The zero value matches all articles.
+type ArticleFilter struct {
+	// Tags matches articles that have at least one of the tags. Tag names are case insensitive.
+	Tags        []string
+	Since       time.Time
+	PrivateOnly bool
+	PublicOnly  bool
+}
+
+// Match reports whether the article satisfies all the conditions of the filter.
+func (f *ArticleFilter) Match(a *Article) bool {
+	if f.PrivateOnly && !a.Private {
+		return false
+	}
+	if f.PublicOnly && a.Private {
+		return false
+	}
+	if !f.Since.IsZero() && a.Item.UpdatedAt.Before(f.Since) {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, tag := range MarshalTag(a.Tags) {
+		for _, want := range f.Tags {
+			if strings.EqualFold(tag.Name, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsZero reports whether the filter has no conditions.
+func (f *ArticleFilter) IsZero() bool {
+	return len(f.Tags) == 0 && f.Since.IsZero() && !f.PrivateOnly && !f.PublicOnly
+}
+
+// FilterArticles returns the articles that match the filter.
+func FilterArticles(articles []*Article, f *ArticleFilter) []*Article {
+	var filtered []*Article
+	for _, a := range articles {
+		if f.Match(a) {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,39 @@
+package qiisync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArticleFilterMatch(t *testing.T) {
+	a := &Article{
+		ArticleHeader: &ArticleHeader{
+			ID:      "1234567890abcdefghij",
+			Title:   "はじめてのGo",
+			Tags:    "Go:1.14,Python",
+			Private: true,
+		},
+		Item: &Item{UpdatedAt: time.Date(2020, 4, 22, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name   string
+		filter *ArticleFilter
+		want   bool
+	}{
+		{name: "zero", filter: &ArticleFilter{}, want: true},
+		{name: "tag", filter: &ArticleFilter{Tags: []string{"go"}}, want: true},
+		{name: "tag_unmatched", filter: &ArticleFilter{Tags: []string{"Ruby"}}, want: false},
+		{name: "since", filter: &ArticleFilter{Since: time.Date(2020, 4, 22, 0, 0, 0, 0, time.UTC)}, want: true},
+		{name: "since_unmatched", filter: &ArticleFilter{Since: time.Date(2020, 4, 23, 0, 0, 0, 0, time.UTC)}, want: false},
+		{name: "private_only", filter: &ArticleFilter{PrivateOnly: true}, want: true},
+		{name: "public_only", filter: &ArticleFilter{PublicOnly: true}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(a); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
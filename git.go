@@ -0,0 +1,221 @@
+package qiisync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The modes of committing the articles stored by pull.
+const (
+	// GitCommitRun commits all the articles stored by a pull at once.
+	GitCommitRun = "run"
+	// GitCommitArticle commits the articles stored by a pull one by one.
+	GitCommitArticle = "article"
+)
+
+const prePushHookName = "pre-push"
+
+// prePushHookMarker identifies the hook installed by qiisync, so that it can be overwritten.
+const prePushHookMarker = "# qiisync pre-push hook"
+
+// prePushHook checks the articles changed by the pushed commits.
+// The remote object name is all zeros when a new branch is pushed, and the local one is all zeros
+// when a branch is deleted. The remote commit is not found locally when the remote branch has
+// commits not fetched yet, so the changes cannot be determined.
+const prePushHook = `#!/bin/sh
+` + prePushHookMarker + `. Installed by "qiisync hook install".
+# It checks the articles changed by the pushed commits with "qiisync push --dry-run".
+while read local_ref local_sha remote_ref remote_sha
+do
+	case $local_sha in
+	*[!0]*) ;;
+	*) continue ;;
+	esac
+	case $remote_sha in
+	*[!0]*)
+		if ! git cat-file -e "$remote_sha^{commit}" 2>/dev/null; then
+			echo "qiisync: $remote_ref ($remote_sha) is not found locally. run \"git fetch\" and push again" >&2
+			exit 1
+		fi
+		qiisync push --dry-run --since "$remote_sha..$local_sha" || exit 1 ;;
+	*) qiisync push --dry-run || exit 1 ;;
+	esac
+done
+`
+
+// ValidateGitCommitMode reports whether mode is one of the modes of committing, or empty not to commit.
+func ValidateGitCommitMode(mode string) error {
+	switch mode {
+	case "", GitCommitRun, GitCommitArticle:
+		return nil
+	}
+	return fmt.Errorf("unknown git commit mode: %s. use %s or %s", mode, GitCommitRun, GitCommitArticle)
+}
+
+// git runs git in dir and returns its standard output.
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// CommitPulled commits the files of the articles stored by pull to the git repository of base_dir.
+// The FilePath of the articles must be where they are stored. Only their files are committed,
+// so the changes the user has staged are left as they are.
+func (b *Broker) CommitPulled(articles []*Article, mode string) error {
+	if err := ValidateGitCommitMode(mode); err != nil {
+		return err
+	}
+	if mode == "" || len(articles) == 0 {
+		return nil
+	}
+	if mode == GitCommitArticle {
+		for _, a := range articles {
+			if err := b.gitCommit(pullCommitMessage([]*Article{a}), b.gitFiles(a)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var files []string
+	for _, a := range articles {
+		files = append(files, b.gitFiles(a)...)
+	}
+	return b.gitCommit(pullCommitMessage(articles), files)
+}
+
+// gitFiles returns the absolute paths of the existing files stored for the article by pull.
+func (b *Broker) gitFiles(a *Article) []string {
+	candidates := []string{
+		a.FilePath,
+		b.htmlPath(a.FilePath),
+		commentsPath(a.FilePath),
+		filepath.Join(filepath.Dir(a.FilePath), imageDirName, a.ID),
+	}
+	var files []string
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			files = append(files, abs)
+		}
+	}
+	return files
+}
+
+// gitCommit commits files with message if any of them is changed.
+func (b *Broker) gitCommit(message string, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if _, err := git(b.baseDir(), append([]string{"add", "--all", "--"}, files...)...); err != nil {
+		return err
+	}
+	// "git diff --quiet" exits with 1 if there are differences.
+	_, err := git(b.baseDir(), append([]string{"diff", "--cached", "--quiet", "--"}, files...)...)
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		return err
+	}
+	if _, err := git(b.baseDir(), append([]string{"commit", "--quiet", "--message", message, "--"}, files...)...); err != nil {
+		return err
+	}
+	Logf("git", "commit %s", strings.SplitN(message, "\n", 2)[0])
+	return nil
+}
+
+// pullCommitMessage summarizes the articles stored by pull with their updated_at on Qiita.
+func pullCommitMessage(articles []*Article) string {
+	var sb strings.Builder
+	if len(articles) == 1 {
+		fmt.Fprintf(&sb, "qiisync pull: %s\n\n", articles[0].Title)
+	} else {
+		fmt.Fprintf(&sb, "qiisync pull: %d articles\n\n", len(articles))
+	}
+	for _, a := range articles {
+		fmt.Fprintf(&sb, "- %s (%s) updated at %s\n", a.Title, a.ID, a.Item.UpdatedAt.Format(time.RFC3339))
+	}
+	return sb.String()
+}
+
+// ChangedArticleFiles returns the local articles changed since ref, including the uncommitted
+// changes and the files not tracked by git yet. If ref is a range of commits like "A..B",
+// only the articles changed by the commits in it are returned.
+func (b *Broker) ChangedArticleFiles(ref string) ([]string, error) {
+	diff, err := git(b.baseDir(), "diff", "--name-only", "--relative", "-z", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(ref, "..") {
+		untracked, err := git(b.baseDir(), "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, err
+		}
+		diff += untracked
+	}
+	changed := make(map[string]bool)
+	for _, rel := range strings.Split(diff, "\x00") {
+		if rel != "" {
+			changed[filepath.Join(b.baseDir(), filepath.FromSlash(rel))] = true
+		}
+	}
+
+	files, err := b.LocalArticleFiles()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, f := range files {
+		if changed[f] {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// InstallPrePushHook installs the git hook that runs "qiisync push --dry-run" before pushing
+// to the git repository of base_dir, and returns its path. A hook not installed by qiisync
+// is overwritten only if force is true.
+func (b *Broker) InstallPrePushHook(force bool) (string, error) {
+	out, err := git(b.baseDir(), "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(b.baseDir(), dir)
+	}
+	p := filepath.Join(dir, prePushHookName)
+
+	if d, err := ioutil.ReadFile(p); err == nil {
+		if !force && !strings.Contains(string(d), prePushHookMarker) {
+			return "", fmt.Errorf("%s already exists. use --force to overwrite it", p)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(p, []byte(prePushHook), 0755); err != nil {
+		return "", err
+	}
+	return p, nil
+}
@@ -0,0 +1,309 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setupGitRepo creates a broker whose base_dir is a new git repository.
+func setupGitRepo(t *testing.T) *Broker {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Fatalf("create tempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"config", "user.name", "qiisync"},
+		{"config", "user.email", "qiisync@example.com"},
+	} {
+		if _, err := git(tempDir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewBroker(&Config{Local: localConfig{Dir: tempDir}})
+}
+
+func gitOutput(t *testing.T, b *Broker, args ...string) string {
+	t.Helper()
+	out, err := git(b.baseDir(), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out)
+}
+
+func TestCommitPulled(t *testing.T) {
+	b := setupGitRepo(t)
+
+	article := func(id, title, body string) *Article {
+		return &Article{
+			ArticleHeader: &ArticleHeader{ID: id, Title: title, Tags: "Go"},
+			Item:          &Item{Body: body, UpdatedAt: time.Date(2020, 4, 23, 5, 41, 35, 0, time.UTC)},
+			FilePath:      filepath.Join(b.baseDir(), "20200423", title+".md"),
+		}
+	}
+	pull := func(articles ...*Article) {
+		for _, a := range articles {
+			if err := b.store(a.FilePath, a); err != nil {
+				t.Fatalf("store(): %v", err)
+			}
+		}
+	}
+
+	// The changes staged by the user are not committed.
+	if err := ioutil.WriteFile(filepath.Join(b.baseDir(), "notes.txt"), []byte("notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(t, b, "add", "notes.txt")
+
+	first := []*Article{
+		article("c686397e4a0f4f11683d", "First", "# First\n"),
+		article("1234567890abcdefghij", "Second", "# Second\n"),
+	}
+	pull(first...)
+	if err := b.CommitPulled(first, GitCommitArticle); err != nil {
+		t.Errorf("CommitPulled(): %v", err)
+		return
+	}
+
+	second := []*Article{
+		article("c686397e4a0f4f11683d", "First", "# First updated\n"),
+		article("1234567890abcdefghij", "Second", "# Second updated\n"),
+	}
+	pull(second...)
+	if err := b.CommitPulled(second, GitCommitRun); err != nil {
+		t.Errorf("CommitPulled(): %v", err)
+		return
+	}
+	// Nothing is committed if the articles are not changed.
+	if err := b.CommitPulled(second, GitCommitRun); err != nil {
+		t.Errorf("CommitPulled(): %v", err)
+		return
+	}
+
+	got := gitOutput(t, b, "log", "--format=%s")
+	want := "qiisync pull: 2 articles\nqiisync pull: Second\nqiisync pull: First"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commits mismatch (-want +got):\n%s", diff)
+	}
+	got = gitOutput(t, b, "log", "-1", "--format=%b")
+	want = "- First (c686397e4a0f4f11683d) updated at 2020-04-23T05:41:35Z\n- Second (1234567890abcdefghij) updated at 2020-04-23T05:41:35Z"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commit message mismatch (-want +got):\n%s", diff)
+	}
+	if got := gitOutput(t, b, "diff", "--cached", "--name-only"); got != "notes.txt" {
+		t.Errorf("staged files = %q, want notes.txt", got)
+	}
+
+	if err := b.CommitPulled(second, "daily"); err == nil {
+		t.Errorf("CommitPulled() succeeded with an unknown mode")
+	}
+}
+
+func TestChangedArticleFiles(t *testing.T) {
+	b := setupGitRepo(t)
+
+	write := func(name, content string) string {
+		p := filepath.Join(b.baseDir(), name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	write("20200423/unchanged.md", "# Unchanged\n")
+	write("20200423/changed.md", "# Changed\n")
+	write("20200424/committed.md", "# Committed\n")
+	gitOutput(t, b, "add", "--all")
+	gitOutput(t, b, "commit", "--quiet", "--message", "first")
+	base := gitOutput(t, b, "rev-parse", "HEAD")
+
+	write("20200424/committed.md", "# Committed again\n")
+	gitOutput(t, b, "commit", "--quiet", "--all", "--message", "second")
+
+	want := []string{
+		write("20200423/changed.md", "# Changed again\n"),
+		filepath.Join(b.baseDir(), "20200424", "committed.md"),
+		write("20200425/new.md", "# New\n"),
+	}
+	write("20200425/notes.txt", "notes\n")
+
+	got, err := b.ChangedArticleFiles(base)
+	if err != nil {
+		t.Errorf("ChangedArticleFiles(): %v", err)
+		return
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ChangedArticleFiles() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The range has only the committed changes, without the working tree and the untracked files.
+	got, err = b.ChangedArticleFiles(base + "..HEAD")
+	if err != nil {
+		t.Errorf("ChangedArticleFiles(): %v", err)
+		return
+	}
+	if diff := cmp.Diff(want[1:2], got); diff != "" {
+		t.Errorf("ChangedArticleFiles() of the range mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := b.ChangedArticleFiles("no-such-ref"); err == nil {
+		t.Errorf("ChangedArticleFiles() succeeded with a ref that does not exist")
+	}
+}
+
+func TestInstallPrePushHook(t *testing.T) {
+	b := setupGitRepo(t)
+
+	p, err := b.InstallPrePushHook(false)
+	if err != nil {
+		t.Errorf("InstallPrePushHook(): %v", err)
+		return
+	}
+	if want := filepath.Join(b.baseDir(), ".git", "hooks", "pre-push"); p != want {
+		t.Errorf("InstallPrePushHook() = %s, want %s", p, want)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Errorf("stat hook: %v", err)
+		return
+	}
+	if fi.Mode().Perm()&0100 == 0 {
+		t.Errorf("hook is not executable: %v", fi.Mode())
+	}
+
+	// The hook installed by qiisync is updated.
+	if _, err := b.InstallPrePushHook(false); err != nil {
+		t.Errorf("InstallPrePushHook(): %v", err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte("#!/bin/sh\nmake test\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.InstallPrePushHook(false); err == nil {
+		t.Errorf("InstallPrePushHook() overwrote the hook of the user")
+	}
+	if _, err := b.InstallPrePushHook(true); err != nil {
+		t.Errorf("InstallPrePushHook(): %v", err)
+	}
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Errorf("read hook: %v", err)
+		return
+	}
+	if string(d) != prePushHook {
+		t.Errorf("hook = %q, want %q", d, prePushHook)
+	}
+}
+
+func TestPrePushHook(t *testing.T) {
+	b := setupGitRepo(t)
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	p := filepath.Join(b.baseDir(), "20200423", "article.md")
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte("# Article\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(t, b, "add", "--all")
+	gitOutput(t, b, "commit", "--quiet", "--message", "first")
+	remote := gitOutput(t, b, "rev-parse", "HEAD")
+	gitOutput(t, b, "commit", "--quiet", "--allow-empty", "--message", "second")
+	local := gitOutput(t, b, "rev-parse", "HEAD")
+
+	// The qiisync run by the hook only records its arguments.
+	bin, err := filepath.Abs(filepath.Join(b.baseDir(), ".bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stub := "#!/bin/sh\necho \"$@\" >> \"$(dirname \"$0\")/args\"\n"
+	if err := ioutil.WriteFile(filepath.Join(bin, "qiisync"), []byte(stub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(bin, "pre-push")
+	if err := ioutil.WriteFile(hook, []byte(prePushHook), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	zero := strings.Repeat("0", 40)
+	missing := strings.Repeat("1", 40)
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantErr  string
+		wantFail bool
+	}{
+		{
+			name:  "update",
+			input: "refs/heads/main " + local + " refs/heads/main " + remote + "\n",
+			want:  "push --dry-run --since " + remote + ".." + local + "\n",
+		},
+		{
+			name:  "new_branch",
+			input: "refs/heads/main " + local + " refs/heads/main " + zero + "\n",
+			want:  "push --dry-run\n",
+		},
+		{
+			name:  "delete",
+			input: "(delete) " + zero + " refs/heads/main " + remote + "\n",
+		},
+		{
+			name:     "remote_not_fetched",
+			input:    "refs/heads/main " + local + " refs/heads/main " + missing + "\n",
+			wantErr:  "refs/heads/main (" + missing + ") is not found locally",
+			wantFail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := filepath.Join(bin, "args")
+			if err := os.RemoveAll(args); err != nil {
+				t.Fatal(err)
+			}
+			var stderr strings.Builder
+			cmd := exec.Command("sh", hook)
+			cmd.Dir = b.baseDir()
+			cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+			cmd.Stdin = strings.NewReader(tt.input)
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); (err != nil) != tt.wantFail {
+				t.Errorf("pre-push error = %v, wantFail %v: %s", err, tt.wantFail, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("pre-push stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+			d, err := ioutil.ReadFile(args)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(d)); diff != "" {
+				t.Errorf("pre-push mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
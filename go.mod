@@ -8,6 +8,7 @@ require (
 	github.com/daviddengcn/go-colortext v1.0.0 // indirect
 	github.com/google/go-cmp v0.4.0
 	github.com/motemen/go-colorine v0.0.0-20180816141035-45d19169413a
+	github.com/russross/blackfriday/v2 v2.0.1
 	github.com/urfave/cli/v2 v2.2.0
 	gopkg.in/yaml.v2 v2.2.2
 )
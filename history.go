@@ -0,0 +1,169 @@
+package qiisync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Songmu/flextime"
+)
+
+const historyDirName = "history"
+
+// The actions recorded in the history of an article.
+const (
+	ActionPull = "pull"
+	ActionPush = "push"
+	ActionPost = "post"
+)
+
+// defaultHistoryLimit is the number of the revisions kept per article by default.
+const defaultHistoryLimit = 100
+
+// Revision is a version of an article synchronized with Qiita.
+type Revision struct {
+	Rev    int    `json:"rev"`
+	Action string `json:"action"`
+	// SyncedAt is the local time of the synchronization, and UpdatedAt is updated_at on Qiita.
+	SyncedAt  time.Time `json:"synced_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ArticleHeader
+	Body string `json:"body"`
+}
+
+// Content returns the revision in the format of the local file.
+func (r *Revision) Content(id string) (string, error) {
+	header := r.ArticleHeader
+	header.ID = id
+	a := &Article{
+		ArticleHeader: &header,
+		Item:          &Item{Body: r.Body},
+	}
+	return a.fullContent()
+}
+
+// historyLimit returns the number of the revisions kept per article. A negative history_limit keeps all.
+func (b *Broker) historyLimit() int {
+	if b.Local.HistoryLimit == 0 {
+		return defaultHistoryLimit
+	}
+	return b.Local.HistoryLimit
+}
+
+// historyPath returns the file where the revisions of the article are appended one per line.
+func (b *Broker) historyPath(id string) string {
+	return filepath.Join(b.stateDir(), historyDirName, id+".jsonl")
+}
+
+// History returns the revisions of the article from oldest to newest.
+func (b *Broker) History(id string) ([]*Revision, error) {
+	if id == "" {
+		return nil, errors.New("article ID is required")
+	}
+	f, err := os.Open(b.historyPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var revs []*Revision
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		var r Revision
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("json decode: %w", err)
+		}
+		revs = append(revs, &r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+// Revision returns the revision rev of the article. If rev is 0, the newest revision is returned.
+func (b *Broker) Revision(id string, rev int) (*Revision, error) {
+	revs, err := b.History(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		return nil, fmt.Errorf("no history of article %s", id)
+	}
+	if rev == 0 {
+		return revs[len(revs)-1], nil
+	}
+	for _, r := range revs {
+		if r.Rev == rev {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d of article %s not found", rev, id)
+}
+
+// recordRevision appends the article to its history as the result of action.
+// The oldest revisions are dropped when the history exceeds history_limit.
+func (b *Broker) recordRevision(action string, a *Article) error {
+	revs, err := b.History(a.ID)
+	if err != nil {
+		return err
+	}
+	rev := 1
+	if len(revs) > 0 {
+		rev = revs[len(revs)-1].Rev + 1
+	}
+	r := &Revision{
+		Rev:           rev,
+		Action:        action,
+		SyncedAt:      flextime.Now(),
+		UpdatedAt:     a.Item.UpdatedAt,
+		ArticleHeader: *a.ArticleHeader,
+		Body:          a.Item.Body,
+	}
+	d, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	p := b.historyPath(a.ID)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if limit := b.historyLimit(); limit > 0 && len(revs)+1 > limit {
+		return b.pruneHistory(p, revs[len(revs)+1-limit:], d)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(d, '\n')); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// pruneHistory rewrites the history file at p with the revisions kept and the new one encoded in d.
+func (b *Broker) pruneHistory(p string, kept []*Revision, d []byte) error {
+	var buf bytes.Buffer
+	for _, r := range kept {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.Write(d)
+	buf.WriteByte('\n')
+	return writeFileAtomic(p, buf.Bytes(), 0644)
+}
@@ -0,0 +1,181 @@
+package qiisync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHistory(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		flextime.Restore()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	id := "c686397e4a0f4f11683d"
+	header := ArticleHeader{ID: id, Title: "Example title", Tags: "Go:1.14", Author: "d-tsuji"}
+	scheduled := header
+	scheduled.Private = true
+	scheduled.Organization = "increments"
+	scheduled.Slide = slideHeader(true)
+	scheduled.PublishAt = "2020-05-12 09:00"
+	article := func(h ArticleHeader, body string, updatedAt time.Time) *Article {
+		return &Article{
+			ArticleHeader: &h,
+			Item:          &Item{Body: body, UpdatedAt: updatedAt},
+		}
+	}
+
+	flextime.Fix(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))
+	if err := b.recordRevision(ActionPull, article(header, "# First\n", time.Date(2020, 4, 30, 0, 0, 0, 0, time.UTC))); err != nil {
+		t.Errorf("recordRevision(): %v", err)
+		return
+	}
+	flextime.Fix(time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC))
+	if err := b.recordRevision(ActionPush, article(scheduled, "# Second\n", time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC))); err != nil {
+		t.Errorf("recordRevision(): %v", err)
+		return
+	}
+
+	got, err := b.History(id)
+	if err != nil {
+		t.Errorf("History(): %v", err)
+		return
+	}
+	want := []*Revision{
+		{
+			Rev:           1,
+			Action:        ActionPull,
+			SyncedAt:      time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:     time.Date(2020, 4, 30, 0, 0, 0, 0, time.UTC),
+			ArticleHeader: header,
+			Body:          "# First\n",
+		},
+		{
+			Rev:           2,
+			Action:        ActionPush,
+			SyncedAt:      time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:     time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC),
+			ArticleHeader: scheduled,
+			Body:          "# Second\n",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("History() mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		name    string
+		rev     int
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "newest",
+			rev:  0,
+			want: "---\nID: c686397e4a0f4f11683d\nTitle: Example title\nTags: Go:1.14\nAuthor: d-tsuji\nPrivate: true\nOrganization: increments\nSlide: true\nPublishAt: 2020-05-12 09:00\n---\n\n# Second\n",
+		},
+		{
+			name: "rev_1",
+			rev:  1,
+			want: "---\nID: c686397e4a0f4f11683d\nTitle: Example title\nTags: Go:1.14\nAuthor: d-tsuji\nPrivate: false\n---\n\n# First\n",
+		},
+		{
+			name:    "not_found",
+			rev:     3,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := b.Revision(id, tt.rev)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Revision() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := r.Content(id)
+			if err != nil {
+				t.Errorf("Content(): %v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Content() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if revs, err := b.History("1234567890abcdefghij"); err != nil || len(revs) != 0 {
+		t.Errorf("History() of an article without history = %v, %v", revs, err)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	// The revisions recorded before the header was embedded are still read.
+	id := "c686397e4a0f4f11683d"
+	p := b.historyPath(id)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"rev":1,"action":"pull","synced_at":"2020-05-01T00:00:00Z","updated_at":"2020-04-30T00:00:00Z","title":"Old","tags":"Go","author":"d-tsuji","private":true,"body":"# Old\n"}` + "\n"
+	if err := ioutil.WriteFile(p, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Local.HistoryLimit = 2
+	for _, body := range []string{"# 2\n", "# 3\n", "# 4\n"} {
+		a := &Article{ArticleHeader: &ArticleHeader{ID: id, Title: "New"}, Item: &Item{Body: body}}
+		if err := b.recordRevision(ActionPush, a); err != nil {
+			t.Errorf("recordRevision(): %v", err)
+			return
+		}
+	}
+	revs, err := b.History(id)
+	if err != nil {
+		t.Errorf("History(): %v", err)
+		return
+	}
+	var got []string
+	for _, r := range revs {
+		got = append(got, fmt.Sprintf("%d %s", r.Rev, r.Body))
+	}
+	// The oldest revisions are dropped, and the numbers of the revisions are kept.
+	want := []string{"3 # 3\n", "4 # 4\n"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("History() mismatch (-want +got):\n%s", diff)
+	}
+
+	b.Local.HistoryLimit = 0
+	if err := ioutil.WriteFile(p, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := b.Revision(id, 1)
+	if err != nil {
+		t.Errorf("Revision(): %v", err)
+		return
+	}
+	want1 := ArticleHeader{Title: "Old", Tags: "Go", Author: "d-tsuji", Private: true}
+	if diff := cmp.Diff(want1, r.ArticleHeader); diff != "" {
+		t.Errorf("Revision() of the legacy format mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,142 @@
+package qiisync
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName is the file in base_dir that lists the files not treated as articles
+// in the same syntax as .gitignore.
+const ignoreFileName = ".qiisyncignore"
+
+type ignoreRule struct {
+	reg     *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches the paths relative to base_dir against the rules of .qiisyncignore.
+type ignoreMatcher struct {
+	rules []*ignoreRule
+}
+
+// loadIgnoreFile reads the rules from the file at path.
+// If the file does not exist, the matcher ignores nothing.
+func loadIgnoreFile(path string) (*ignoreMatcher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ignoreMatcher{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &ignoreMatcher{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		r, err := parseIgnoreRule(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if r != nil {
+			m.rules = append(m.rules, r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseIgnoreRule parses a line of .qiisyncignore. It returns nil for blank lines and comments.
+func parseIgnoreRule(line string) (*ignoreRule, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	r := &ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// "\#" and "\!" start a pattern with the literal character.
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil, nil
+	}
+	// A pattern without a slash matches at any depth, otherwise it is relative to base_dir.
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	line = strings.TrimPrefix(line, "/")
+
+	reg, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return nil, err
+	}
+	r.reg = reg
+	return r, nil
+}
+
+// globToRegexp converts the glob pattern of .gitignore to a regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+// match reports whether the slash-separated path relative to base_dir is ignored.
+// As .gitignore, the last matching rule wins.
+func (m *ignoreMatcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.reg.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,53 @@
+package qiisync
+
+import (
+	"testing"
+)
+
+func Test_ignoreMatcher(t *testing.T) {
+	var rules []*ignoreRule
+	for _, line := range []string{
+		"# comment",
+		"",
+		"drafts/",
+		"*.tmp.md",
+		"!keep.tmp.md",
+		"/private/*.md",
+		"archive/**/old.md",
+		`\#hash.md`,
+	} {
+		r, err := parseIgnoreRule(line)
+		if err != nil {
+			t.Fatalf("parseIgnoreRule(%q): %v", line, err)
+		}
+		if r != nil {
+			rules = append(rules, r)
+		}
+	}
+	m := &ignoreMatcher{rules: rules}
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{rel: "drafts", isDir: true, want: true},
+		{rel: "2020/drafts", isDir: true, want: true},
+		{rel: "drafts", isDir: false, want: false},
+		{rel: "a.tmp.md", want: true},
+		{rel: "20200101/a.tmp.md", want: true},
+		{rel: "keep.tmp.md", want: false},
+		{rel: "private/a.md", want: true},
+		{rel: "private/sub/a.md", want: false},
+		{rel: "sub/private/a.md", want: false},
+		{rel: "archive/old.md", want: true},
+		{rel: "archive/2019/12/old.md", want: true},
+		{rel: "#hash.md", want: true},
+		{rel: "article.md", want: false},
+	}
+	for _, tt := range tests {
+		if got := m.match(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("match(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
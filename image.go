@@ -0,0 +1,234 @@
+package qiisync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const imageDirName = "images"
+
+var (
+	markdownImageReg = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^\s)>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	htmlImageReg     = regexp.MustCompile(`(?i)<img\s[^>]*?src\s*=\s*["']([^"']+)["']`)
+)
+
+// replaceImageLinks replaces the links of images in Markdown and <img> tags with the result of replace.
+// The links in code blocks are left as they are, since they are samples rather than images.
+func replaceImageLinks(body string, replace func(link string) string) string {
+	var (
+		sb    strings.Builder
+		text  strings.Builder
+		fence codeFence
+	)
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if fence.inside() {
+			fence.closes(line)
+			sb.WriteString(line)
+			continue
+		}
+		if fence.opens(line) {
+			sb.WriteString(replaceImageLinksInText(text.String(), replace))
+			text.Reset()
+			sb.WriteString(line)
+			continue
+		}
+		text.WriteString(line)
+	}
+	sb.WriteString(replaceImageLinksInText(text.String(), replace))
+	return sb.String()
+}
+
+func replaceImageLinksInText(text string, replace func(link string) string) string {
+	for _, reg := range []*regexp.Regexp{markdownImageReg, htmlImageReg} {
+		var sb strings.Builder
+		last := 0
+		for _, m := range reg.FindAllStringSubmatchIndex(text, -1) {
+			sb.WriteString(text[last:m[2]])
+			sb.WriteString(replace(text[m[2]:m[3]]))
+			last = m[3]
+		}
+		sb.WriteString(text[last:])
+		text = sb.String()
+	}
+	return text
+}
+
+// imageLinks returns the links of images in body without duplicates.
+func imageLinks(body string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	replaceImageLinks(body, func(link string) string {
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+		return link
+	})
+	return links
+}
+
+func isRemoteLink(link string) bool {
+	return strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://")
+}
+
+// DownloadImages downloads the remote images in the article into the "images/<id>" directory
+// next to the file of the article.
+//
+// If rewrite_image_links is set, the links in the article are replaced with the relative paths
+// of the downloaded images. The original URLs are recorded in the state directory so that
+// UploadFresh can restore them.
+func (b *Broker) DownloadImages(articlePath string, a *Article) error {
+	mapping, err := b.loadImageMapping(a.ID)
+	if err != nil {
+		return err
+	}
+	articleDir := filepath.Dir(articlePath)
+	downloaded := make(map[string]string, len(mapping))
+	for rel, link := range mapping {
+		if _, err := os.Stat(filepath.Join(articleDir, filepath.FromSlash(rel))); err == nil {
+			downloaded[link] = rel
+		}
+	}
+
+	for _, link := range imageLinks(a.Item.Body) {
+		if !isRemoteLink(link) {
+			continue
+		}
+		if _, exists := downloaded[link]; exists {
+			continue
+		}
+		name, err := b.downloadImage(link, filepath.Join(articleDir, imageDirName, a.ID))
+		if err != nil {
+			// A broken image should not prevent the article from being pulled.
+			Logf("error", "download image: %v", err)
+			continue
+		}
+		rel := path.Join(imageDirName, a.ID, name)
+		Logf("image", "%s ---> %s", link, rel)
+		mapping[rel] = link
+		downloaded[link] = rel
+	}
+
+	if b.Local.RewriteImageLinks {
+		a.Item.Body = replaceImageLinks(a.Item.Body, func(link string) string {
+			if rel, exists := downloaded[link]; exists {
+				return rel
+			}
+			return link
+		})
+	}
+	return b.saveImageMapping(a.ID, mapping)
+}
+
+// downloadImage stores the image of rawurl in dir with the name based on its content
+// and returns the name.
+func (b *Broker) downloadImage(rawurl, dir string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, rawurl, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", rawurl, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])[:16] + imageExtension(rawurl, resp.Header.Get("Content-Type"))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func imageExtension(rawurl, contentType string) string {
+	if u, err := url.Parse(rawurl); err == nil {
+		if ext := strings.ToLower(path.Ext(u.Path)); ext != "" && len(ext) <= 5 {
+			return ext
+		}
+	}
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// restoreImageLinks replaces the relative paths of the downloaded images in the article
+// with the original URLs.
+func (b *Broker) restoreImageLinks(a *Article) (string, error) {
+	mapping, err := b.loadImageMapping(a.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(mapping) == 0 {
+		return a.Item.Body, nil
+	}
+	return replaceImageLinks(a.Item.Body, func(link string) string {
+		if u, exists := mapping[link]; exists {
+			return u
+		}
+		return link
+	}), nil
+}
+
+func (b *Broker) imageMappingPath(id string) string {
+	return filepath.Join(b.stateDir(), imageDirName, id+".json")
+}
+
+// loadImageMapping returns the map from the relative paths of the downloaded images
+// to their original URLs.
+func (b *Broker) loadImageMapping(id string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if id == "" {
+		return mapping, nil
+	}
+	d, err := ioutil.ReadFile(b.imageMappingPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return mapping, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(d, &mapping); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	return mapping, nil
+}
+
+func (b *Broker) saveImageMapping(id string, mapping map[string]string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+	p := b.imageMappingPath(id)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	d, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p, d, 0644)
+}
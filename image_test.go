@@ -0,0 +1,94 @@
+package qiisync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImageLinks(t *testing.T) {
+	body := `# Images
+
+![fig1](https://qiita-image-store.s3.amazonaws.com/0/1/fig1.png)
+![fig2](https://qiita-image-store.s3.amazonaws.com/0/1/fig2.png "title")
+<img width="300" src="https://qiita-image-store.s3.amazonaws.com/0/1/fig3.png">
+![local](./fig4.png)
+![fig1 again](https://qiita-image-store.s3.amazonaws.com/0/1/fig1.png)
+[not image](https://qiita.com)
+`
+	got := imageLinks(body)
+	want := []string{
+		"https://qiita-image-store.s3.amazonaws.com/0/1/fig1.png",
+		"https://qiita-image-store.s3.amazonaws.com/0/1/fig2.png",
+		"./fig4.png",
+		"https://qiita-image-store.s3.amazonaws.com/0/1/fig3.png",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("imageLinks() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDownloadImages(t *testing.T) {
+	b, mux, serverURL, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	b.Local.RewriteImageLinks = true
+
+	mux.HandleFunc("/images/fig1.png", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("the token must not be sent to the image server")
+		}
+		fmt.Fprint(w, "fig1")
+	})
+	mux.HandleFunc("/images/broken.png", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	remoteBody := fmt.Sprintf("![fig1](%[1]s/images/fig1.png)\n<img src=\"%[1]s/images/fig1.png\">\n![broken](%[1]s/images/broken.png)\n", serverURL)
+	a := &Article{
+		ArticleHeader: &ArticleHeader{ID: "c686397e4a0f4f11683d"},
+		Item:          &Item{ID: "c686397e4a0f4f11683d", Body: remoteBody},
+	}
+	articlePath := filepath.Join(b.baseDir(), "20000101", "test.md")
+	if err := b.DownloadImages(articlePath, a); err != nil {
+		t.Errorf("DownloadImages(): %v", err)
+		return
+	}
+
+	sum := sha256.Sum256([]byte("fig1"))
+	rel := "images/c686397e4a0f4f11683d/" + hex.EncodeToString(sum[:])[:16] + ".png"
+	fbyte, err := ioutil.ReadFile(filepath.Join(b.baseDir(), "20000101", filepath.FromSlash(rel)))
+	if err != nil {
+		t.Errorf("read image: %v", err)
+		return
+	}
+	if string(fbyte) != "fig1" {
+		t.Errorf("downloaded image = %q, want %q", string(fbyte), "fig1")
+	}
+
+	want := fmt.Sprintf("![fig1](%[1]s)\n<img src=\"%[1]s\">\n![broken](%[2]s/images/broken.png)\n", rel, serverURL)
+	if a.Item.Body != want {
+		t.Errorf("rewritten body = %v, want %v", a.Item.Body, want)
+	}
+
+	got, err := b.restoreImageLinks(a)
+	if err != nil {
+		t.Errorf("restoreImageLinks(): %v", err)
+		return
+	}
+	if got != remoteBody {
+		t.Errorf("restoreImageLinks() = %v, want %v", got, remoteBody)
+	}
+}
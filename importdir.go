@@ -0,0 +1,522 @@
+package qiisync
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Songmu/flextime"
+	"gopkg.in/yaml.v2"
+)
+
+// The formats of the repositories articles are imported from.
+const (
+	ImportFromZenn     = "zenn"
+	ImportFromHugo     = "hugo"
+	ImportFromMarkdown = "markdown"
+)
+
+var (
+	zennBlockReg    = regexp.MustCompile(`^(:{3,})\s*(message|details)(?:\s+(.*))?$`)
+	zennCloseReg    = regexp.MustCompile(`^:{3,}$`)
+	zennEmbedReg    = regexp.MustCompile(`^@\[(\w+)\]\((\S+)\)$`)
+	zennFootnoteReg = regexp.MustCompile(`\^\[[^\]]*\]`)
+	zennImageReg    = regexp.MustCompile(`(!\[[^\]]*\]\(\S+)\s+=\d*x\d*\)`)
+
+	shortcodeReg      = regexp.MustCompile(`\{\{[<%]\s*(/?)(\w+)\s*(.*?)\s*[>%]\}\}`)
+	shortcodeParamReg = regexp.MustCompile(`(\w+)="([^"]*)"|"([^"]*)"|(\S+)`)
+	fenceAttrReg      = regexp.MustCompile(`\s*\{(.*)\}$`)
+	titleAttrReg      = regexp.MustCompile(`title="([^"]*)"`)
+
+	githubAlertReg = regexp.MustCompile(`^>\s*\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*$`)
+	h1Reg          = regexp.MustCompile(`^#\s+(.+?)\s*#*$`)
+
+	// githubAlertTypes map the types of the alerts of GitHub to the types of :::note blocks.
+	githubAlertTypes = map[string]string{
+		"NOTE":      "info",
+		"TIP":       "info",
+		"IMPORTANT": "info",
+		"WARNING":   "warn",
+		"CAUTION":   "alert",
+	}
+)
+
+// DirImportResult is the result of importing a file of another blog into base_dir.
+type DirImportResult struct {
+	Source string
+	// Path is the new article in base_dir, or empty if the file is not imported.
+	Path string
+	// Issues are the constructs that could not be converted to Qiita Markdown.
+	Issues []*ImportIssue
+	Err    error
+}
+
+// ImportIssue is a construct in the source file that could not be converted.
+type ImportIssue struct {
+	Line    int
+	Message string
+}
+
+func (i *ImportIssue) String() string {
+	if i.Line == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("%d: %s", i.Line, i.Message)
+}
+
+// ImportDir imports the articles in dir, a repository of from, "zenn", "hugo" or "markdown",
+// into base_dir as new articles that have not been posted yet. The front matter is converted to
+// the header and the syntax of the blog to Qiita Markdown. The files that already exist in base_dir
+// are never overwritten, and an article that fails to be imported is reported in the result.
+func (b *Broker) ImportDir(from, dir string) ([]*DirImportResult, error) {
+	files, err := importSourceFiles(from, dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*DirImportResult, len(files))
+	for i, f := range files {
+		results[i] = b.importFile(from, f)
+	}
+	return results, nil
+}
+
+// importSourceFiles returns the Markdown files of the articles in the repository.
+// The articles of Zenn are in articles and the ones of Hugo are in content, if they exist.
+func importSourceFiles(from, dir string) ([]string, error) {
+	root := dir
+	switch from {
+	case ImportFromZenn:
+		root = filepath.Join(dir, "articles")
+	case ImportFromHugo:
+		root = filepath.Join(dir, "content")
+	case ImportFromMarkdown:
+	default:
+		return nil, fmt.Errorf("unknown import format: %s. use %s, %s or %s", from, ImportFromZenn, ImportFromHugo, ImportFromMarkdown)
+	}
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		root = dir
+	}
+
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// _index.md of Hugo is the page of a section, not an article.
+		if name == "_index.md" {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".md", ".markdown":
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (b *Broker) importFile(from, src string) *DirImportResult {
+	r := &DirImportResult{Source: src}
+	d, err := ioutil.ReadFile(src)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	fm, body, offset, err := splitFrontMatter(string(d))
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	report := func(line int, format string, args ...interface{}) {
+		// Line 0 is about the whole article, such as the front matter.
+		if line > 0 {
+			line += offset
+		}
+		r.Issues = append(r.Issues, &ImportIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	title := frontMatterString(fm, "title")
+	if title == "" {
+		title, body = extractTitle(body)
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	}
+
+	tagKey := "tags"
+	if from == ImportFromZenn {
+		tagKey = "topics"
+	}
+	tags := frontMatterStrings(fm, tagKey)
+	for i := range tags {
+		// Qiita does not allow spaces in tags.
+		tags[i] = strings.Join(strings.Fields(tags[i]), "-")
+	}
+	if len(tags) > maxTags {
+		report(0, "only the first %d of %d tags are imported", maxTags, len(tags))
+		tags = tags[:maxTags]
+	}
+
+	var private bool
+	switch from {
+	case ImportFromZenn:
+		// Zenn articles are unpublished unless published is true.
+		published, _ := fm["published"].(bool)
+		private = !published
+	case ImportFromHugo:
+		private, _ = fm["draft"].(bool)
+	default:
+		draft, _ := fm["draft"].(bool)
+		priv, _ := fm["private"].(bool)
+		private = draft || priv
+	}
+
+	created := frontMatterTime(fm, "date")
+	if created.IsZero() {
+		created = flextime.Now()
+	}
+
+	a := &Article{
+		ArticleHeader: &ArticleHeader{Title: title, Tags: strings.Join(tags, ","), Private: private},
+		Item:          &Item{Title: title, Body: strings.TrimLeft(convertToQiita(from, body, report), "\n"), CreatedAt: created},
+		FilePath:      b.newArticlePath(title, created),
+	}
+	if err := b.createArticle(a); err != nil {
+		r.Err = err
+		return r
+	}
+	r.Path = a.FilePath
+	return r
+}
+
+// splitFrontMatter splits content into the front matter in YAML ("---") or TOML ("+++") and the body.
+// offset is the number of the lines before the body.
+func splitFrontMatter(content string) (fm map[string]interface{}, body string, offset int, err error) {
+	fm = make(map[string]interface{})
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	var delim string
+	switch {
+	case strings.HasPrefix(content, "---\n"):
+		delim = "---"
+	case strings.HasPrefix(content, "+++\n"):
+		delim = "+++"
+	default:
+		return fm, content, 0, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == delim {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, "", 0, fmt.Errorf("front matter is not closed with %s", delim)
+	}
+	raw := strings.Join(lines[1:end], "\n")
+	if delim == "---" {
+		err = yaml.Unmarshal([]byte(raw), &fm)
+	} else {
+		_, err = toml.Decode(raw, &fm)
+	}
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("front matter: %w", err)
+	}
+	return fm, strings.Join(lines[end+1:], "\n"), end + 1, nil
+}
+
+func frontMatterString(fm map[string]interface{}, key string) string {
+	s, _ := fm[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// frontMatterStrings returns the list of key, which may be a comma separated string.
+func frontMatterStrings(fm map[string]interface{}, key string) []string {
+	var values []string
+	switch v := fm[key].(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if s := strings.TrimSpace(fmt.Sprint(e)); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// frontMatterTime returns the time of key, or the zero value of time.Time if it is not a time.
+func frontMatterTime(fm map[string]interface{}, key string) time.Time {
+	switch v := fm[key].(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// extractTitle takes the first level 1 heading out of body as the title,
+// since Qiita shows the title apart from the body.
+func extractTitle(body string) (string, string) {
+	lines := strings.Split(body, "\n")
+	var fence codeFence
+	for i, line := range lines {
+		if !fence.scan(line) {
+			continue
+		}
+		if m := h1Reg.FindStringSubmatch(line); m != nil {
+			// The line is kept blank so that the line numbers of the issues do not shift.
+			lines[i] = ""
+			return m[1], strings.Join(lines, "\n")
+		}
+	}
+	return "", body
+}
+
+// convertToQiita translates the syntax of from in body to Qiita Markdown.
+// The constructs that could not be converted are left as they are and passed to report
+// with their line numbers in body.
+func convertToQiita(from, body string, report func(line int, format string, args ...interface{})) string {
+	var (
+		lines []string
+		fence codeFence
+		// highlight is whether the lines are in a highlight shortcode of Hugo.
+		highlight bool
+		// blocks are the ":::" blocks of Zenn open at the line, "note" or "details".
+		blocks     []string
+		blockDepth []int
+		inAlert    bool
+	)
+	for i, line := range strings.Split(body, "\n") {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+
+		if inAlert {
+			if strings.HasPrefix(trimmed, ">") {
+				lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " "))
+				continue
+			}
+			inAlert = false
+			lines = append(lines, ":::")
+		}
+
+		switch {
+		case highlight:
+			if isShortcode(trimmed, "/highlight") {
+				line = "```"
+				highlight = false
+			}
+		case fence.inside():
+			fence.closes(line)
+		case fence.opens(line):
+			line = fence.marker + convertFenceInfo(from, fence.info(line))
+		case from == ImportFromHugo && isShortcode(trimmed, "highlight"):
+			m := shortcodeReg.FindStringSubmatch(trimmed)
+			params := shortcodeParams(m[3])
+			line = "```"
+			if len(params) > 0 {
+				line += params[0]
+			}
+			highlight = true
+		case from == ImportFromZenn && zennBlockReg.MatchString(trimmed):
+			m := zennBlockReg.FindStringSubmatch(trimmed)
+			if m[2] == "message" {
+				if contains(blocks, "note") {
+					report(n, "nested message cannot be converted")
+				}
+				typ := "info"
+				if strings.TrimSpace(m[3]) == "alert" {
+					typ = "alert"
+				}
+				line = ":::note " + typ
+				blocks = append(blocks, "note")
+			} else {
+				line = "<details><summary>" + html.EscapeString(strings.TrimSpace(m[3])) + "</summary>\n"
+				blocks = append(blocks, "details")
+			}
+			blockDepth = append(blockDepth, len(m[1]))
+		case from == ImportFromZenn && len(blocks) > 0 && zennCloseReg.MatchString(trimmed) && len(trimmed) == blockDepth[len(blockDepth)-1]:
+			last := len(blocks) - 1
+			if blocks[last] == "details" {
+				line = "\n</details>"
+			} else {
+				line = ":::"
+			}
+			blocks, blockDepth = blocks[:last], blockDepth[:last]
+		case from == ImportFromZenn && zennEmbedReg.MatchString(trimmed):
+			line = convertZennEmbed(zennEmbedReg.FindStringSubmatch(trimmed), n, report)
+		case from == ImportFromHugo && shortcodeReg.MatchString(trimmed) && shortcodeReg.FindString(trimmed) == trimmed:
+			line = convertShortcode(shortcodeReg.FindStringSubmatch(trimmed), n, report)
+		case from == ImportFromMarkdown && githubAlertReg.MatchString(trimmed):
+			line = ":::note " + githubAlertTypes[githubAlertReg.FindStringSubmatch(trimmed)[1]]
+			inAlert = true
+		default:
+			line = convertInline(from, line, n, report)
+		}
+		lines = append(lines, line)
+	}
+	if inAlert {
+		lines = append(lines, ":::")
+	}
+	if len(blocks) > 0 {
+		report(0, "%d blocks are not closed", len(blocks))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// convertFenceInfo converts the info string of a code block.
+// "diff js" of Zenn is "diff_js" in Qiita, and the attributes of Hugo like {title="main.go"}
+// are dropped except the title, which is the file name in Qiita.
+func convertFenceInfo(from, info string) string {
+	switch from {
+	case ImportFromZenn:
+		if strings.HasPrefix(info, "diff ") {
+			return "diff_" + strings.TrimSpace(info[len("diff "):])
+		}
+	case ImportFromHugo:
+		if m := fenceAttrReg.FindStringSubmatch(info); m != nil {
+			info = strings.TrimSpace(fenceAttrReg.ReplaceAllString(info, ""))
+			if t := titleAttrReg.FindStringSubmatch(m[1]); t != nil {
+				info += ":" + t[1]
+			}
+		}
+	}
+	return info
+}
+
+// convertZennEmbed converts the embed of Zenn to the URL, which Qiita shows as a card.
+func convertZennEmbed(m []string, n int, report func(int, string, ...interface{})) string {
+	kind, target := m[1], m[2]
+	switch kind {
+	case "card", "tweet", "github", "gist":
+		return target
+	case "youtube":
+		if urlSchemeReg.MatchString(target) {
+			return target
+		}
+		return "https://www.youtube.com/watch?v=" + target
+	}
+	report(n, "embed @[%s] cannot be converted", kind)
+	return m[0]
+}
+
+func isShortcode(s, name string) bool {
+	m := shortcodeReg.FindStringSubmatch(s)
+	return m != nil && m[1]+m[2] == name
+}
+
+// shortcodeParams returns the positional parameters of a shortcode, or the values of the named ones.
+func shortcodeParams(s string) []string {
+	var params []string
+	for _, m := range shortcodeParamReg.FindAllStringSubmatch(s, -1) {
+		params = append(params, m[2]+m[3]+m[4])
+	}
+	return params
+}
+
+// shortcodeNamedParams returns the named parameters of a shortcode.
+func shortcodeNamedParams(s string) map[string]string {
+	params := make(map[string]string)
+	for _, m := range shortcodeParamReg.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			params[m[1]] = m[2]
+		}
+	}
+	return params
+}
+
+// convertShortcode converts the shortcodes of Hugo built in that have the counterparts in Qiita.
+func convertShortcode(m []string, n int, report func(int, string, ...interface{})) string {
+	name, args := m[2], m[3]
+	named := shortcodeNamedParams(args)
+	params := shortcodeParams(args)
+	switch {
+	case m[1] != "":
+	case name == "figure" && named["src"] != "":
+		alt := named["alt"]
+		if alt == "" {
+			alt = named["caption"]
+		}
+		return fmt.Sprintf("![%s](%s)", alt, named["src"])
+	case name == "youtube" && len(params) > 0:
+		id := named["id"]
+		if id == "" {
+			id = params[0]
+		}
+		return "https://www.youtube.com/watch?v=" + id
+	case name == "tweet" && len(params) > 0:
+		if named["user"] != "" && named["id"] != "" {
+			return fmt.Sprintf("https://twitter.com/%s/status/%s", named["user"], named["id"])
+		}
+		return "https://twitter.com/i/status/" + params[len(params)-1]
+	case name == "gist" && len(params) >= 2:
+		return fmt.Sprintf("https://gist.github.com/%s/%s", params[0], params[1])
+	}
+	report(n, "shortcode %s cannot be converted", name)
+	return m[0]
+}
+
+// convertInline converts the inline syntax in line and reports the inline constructs
+// and the relative links that could not be converted.
+func convertInline(from, line string, n int, report func(int, string, ...interface{})) string {
+	if from == ImportFromZenn {
+		if zennImageReg.MatchString(line) {
+			line = zennImageReg.ReplaceAllString(line, "$1)")
+			report(n, "the size of the image is dropped")
+		}
+		if zennFootnoteReg.MatchString(line) {
+			report(n, "inline footnote cannot be converted")
+		}
+	}
+	if from == ImportFromHugo {
+		for _, m := range shortcodeReg.FindAllStringSubmatch(line, -1) {
+			report(n, "shortcode %s cannot be converted", m[2])
+		}
+	}
+	for _, m := range linkReg.FindAllStringSubmatch(line, -1) {
+		link := m[3]
+		// The links by shortcodes are reported as the shortcodes.
+		if urlSchemeReg.MatchString(link) || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "//") || strings.HasPrefix(link, "{{") {
+			continue
+		}
+		report(n, "relative link %s must be fixed after import", link)
+	}
+	return line
+}
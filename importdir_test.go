@@ -0,0 +1,247 @@
+package qiisync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_convertToQiita(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       string
+		body       string
+		want       string
+		wantIssues []string
+	}{
+		{
+			name: "zenn",
+			from: ImportFromZenn,
+			body: ":::message\nNote\n:::\n\n::::details 詳細\n:::message alert\nAlert\n:::\n::::\n\n@[card](https://zenn.dev)\n@[youtube](abc)\n@[speakerdeck](xyz)\n\n```diff js\n:::message\n```\n\n![img](https://example.com/a.png =250x)\nText^[note]\n",
+			want: ":::note info\nNote\n:::\n\n<details><summary>詳細</summary>\n\n:::note alert\nAlert\n:::\n\n</details>\n\nhttps://zenn.dev\nhttps://www.youtube.com/watch?v=abc\n@[speakerdeck](xyz)\n\n```diff_js\n:::message\n```\n\n![img](https://example.com/a.png)\nText^[note]\n",
+			wantIssues: []string{
+				"13: embed @[speakerdeck] cannot be converted",
+				"19: the size of the image is dropped",
+				"20: inline footnote cannot be converted",
+			},
+		},
+		{
+			name: "hugo",
+			from: ImportFromHugo,
+			body: "{{< highlight go >}}\nfunc main() {}\n{{< /highlight >}}\n\n```go {title=\"main.go\" linenos=true}\nx\n```\n\n{{< figure src=\"/a.png\" alt=\"A\" >}}\n{{< youtube abc >}}\n{{< tweet user=\"qiita\" id=\"1\" >}}\n{{< gist spf13 7896402 >}}\n{{< chart >}}\nSee [post]({{< ref \"post.md\" >}}) and [b](../b.md).\n",
+			want: "```go\nfunc main() {}\n```\n\n```go:main.go\nx\n```\n\n![A](/a.png)\nhttps://www.youtube.com/watch?v=abc\nhttps://twitter.com/qiita/status/1\nhttps://gist.github.com/spf13/7896402\n{{< chart >}}\nSee [post]({{< ref \"post.md\" >}}) and [b](../b.md).\n",
+			wantIssues: []string{
+				"13: shortcode chart cannot be converted",
+				"14: shortcode ref cannot be converted",
+				"14: relative link ../b.md must be fixed after import",
+			},
+		},
+		{
+			name: "markdown",
+			from: ImportFromMarkdown,
+			body: "> [!NOTE]\n> Useful\n> information\n\n> [!CAUTION]\n> Danger\n\n> Quote\n\n![img](images/a.png) [top](#top)",
+			want: ":::note info\nUseful\ninformation\n:::\n\n:::note alert\nDanger\n:::\n\n> Quote\n\n![img](images/a.png) [top](#top)",
+			wantIssues: []string{
+				"10: relative link images/a.png must be fixed after import",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var issues []string
+			report := func(line int, format string, args ...interface{}) {
+				issues = append(issues, (&ImportIssue{Line: line, Message: fmt.Sprintf(format, args...)}).String())
+			}
+			got := convertToQiita(tt.from, tt.body, report)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("convertToQiita() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantIssues, issues); diff != "" {
+				t.Errorf("convertToQiita() issues mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_splitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantFM     map[string]interface{}
+		wantBody   string
+		wantOffset int
+		wantErr    bool
+	}{
+		{
+			name:       "yaml",
+			content:    "---\ntitle: Go\ntopics: [go]\n---\nbody\n",
+			wantFM:     map[string]interface{}{"title": "Go", "topics": []interface{}{"go"}},
+			wantBody:   "body\n",
+			wantOffset: 4,
+		},
+		{
+			name:       "toml",
+			content:    "+++\ntitle = \"Go\"\ndraft = true\n+++\r\nbody\r\n",
+			wantFM:     map[string]interface{}{"title": "Go", "draft": true},
+			wantBody:   "body\n",
+			wantOffset: 4,
+		},
+		{
+			name:     "none",
+			content:  "# Go\n",
+			wantFM:   map[string]interface{}{},
+			wantBody: "# Go\n",
+		},
+		{
+			name:    "not closed",
+			content: "---\ntitle: Go\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, offset, err := splitFrontMatter(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitFrontMatter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.wantFM, fm); diff != "" {
+				t.Errorf("splitFrontMatter() mismatch (-want +got):\n%s", diff)
+			}
+			if body != tt.wantBody || offset != tt.wantOffset {
+				t.Errorf("splitFrontMatter() = %q, %d, want %q, %d", body, offset, tt.wantBody, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestImportDir(t *testing.T) {
+	b, _, _, teardown := setup()
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	flextime.Fix(time.Date(2020, 5, 12, 9, 0, 0, 0, time.Local))
+
+	write := func(name, content string) {
+		p := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("zenn/articles/go.md", "---\ntitle: \"はじめてのGo\"\nemoji: \"🐹\"\ntopics: [\"go\", \"next js\", \"a\", \"b\", \"c\", \"d\"]\npublished: true\n---\n\n:::message\nHello\n:::\n@[figma](x)\n")
+	write("zenn/articles/.draft/skip.md", "# Skip\n")
+	write("zenn/README.md", "# Not an article\n")
+	write("hugo/content/_index.md", "# Section\n")
+	write("hugo/content/posts/rust.md", "+++\ntitle = \"Rust\"\ndate = 2020-04-23T10:00:00+09:00\ntags = [\"rust\"]\ndraft = true\n+++\n\nRust\n")
+	write("markdown/notes/memo.md", "# Memo\n\nMemo\n")
+
+	tests := []struct {
+		name string
+		from string
+		want []*DirImportResult
+		// files are the contents of the imported articles relative to base_dir.
+		files map[string]string
+	}{
+		{
+			name: "zenn",
+			from: ImportFromZenn,
+			want: []*DirImportResult{
+				{
+					Source: filepath.Join(tempDir, "zenn", "articles", "go.md"),
+					Path:   filepath.Join(b.baseDir(), "20200512", "はじめてのGo.md"),
+					Issues: []*ImportIssue{
+						{Line: 0, Message: "only the first 5 of 6 tags are imported"},
+						{Line: 11, Message: "embed @[figma] cannot be converted"},
+					},
+				},
+			},
+			files: map[string]string{
+				"20200512/はじめてのGo.md": "---\nID: \"\"\nTitle: はじめてのGo\nTags: go,next-js,a,b,c\nAuthor: \"\"\nPrivate: false\n---\n\n:::note info\nHello\n:::\n@[figma](x)\n",
+			},
+		},
+		{
+			name: "hugo",
+			from: ImportFromHugo,
+			want: []*DirImportResult{
+				{
+					Source: filepath.Join(tempDir, "hugo", "content", "posts", "rust.md"),
+					Path:   filepath.Join(b.baseDir(), "20200423", "Rust.md"),
+				},
+			},
+			files: map[string]string{
+				"20200423/Rust.md": "---\nID: \"\"\nTitle: Rust\nTags: rust\nAuthor: \"\"\nPrivate: true\n---\n\nRust\n",
+			},
+		},
+		{
+			name: "markdown",
+			from: ImportFromMarkdown,
+			want: []*DirImportResult{
+				{
+					Source: filepath.Join(tempDir, "markdown", "notes", "memo.md"),
+					Path:   filepath.Join(b.baseDir(), "20200512", "Memo.md"),
+				},
+			},
+			files: map[string]string{
+				"20200512/Memo.md": "---\nID: \"\"\nTitle: Memo\nTags: \"\"\nAuthor: \"\"\nPrivate: false\n---\n\nMemo\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.ImportDir(tt.from, filepath.Join(tempDir, tt.name))
+			if err != nil {
+				t.Errorf("ImportDir(): %v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ImportDir() mismatch (-want +got):\n%s", diff)
+			}
+			for name, want := range tt.files {
+				d, err := ioutil.ReadFile(filepath.Join(b.baseDir(), filepath.FromSlash(name)))
+				if err != nil {
+					t.Errorf("read file: %v", err)
+					return
+				}
+				if diff := cmp.Diff(want, string(d)); diff != "" {
+					t.Errorf("ImportDir() file %s mismatch (-want +got):\n%s", name, diff)
+				}
+			}
+		})
+	}
+
+	// The existing articles are never overwritten.
+	got, err := b.ImportDir(ImportFromMarkdown, filepath.Join(tempDir, "markdown"))
+	if err != nil {
+		t.Errorf("ImportDir(): %v", err)
+		return
+	}
+	if len(got) != 1 || got[0].Err == nil {
+		t.Errorf("ImportDir() overwrote the existing article: %+v", got)
+	}
+
+	if _, err := b.ImportDir("medium", tempDir); err == nil {
+		t.Errorf("ImportDir() succeeded with an unknown format")
+	}
+}
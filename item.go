@@ -1,12 +1,17 @@
 package qiisync
 
 import (
+	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
 
 const defaultDataFormat = "20060102"
 
+var itemIDReg = regexp.MustCompile(`^[0-9a-zA-Z]{20}$`)
+
 // Item is a structure that represents the QiitaAPI.
 //
 // See also https://qiita.com/api/v2/docs#%E6%8A%95%E7%A8%BF.
@@ -21,6 +26,17 @@ type Item struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 	Tags         []*Tag    `json:"tags"`
 	Private      bool      `json:"private"`
+	Slide        bool      `json:"slide"`
+	// OrganizationURLName is empty if the article does not belong to any organization.
+	OrganizationURLName string `json:"organization_url_name"`
+
+	LikesCount     int `json:"likes_count"`
+	StocksCount    int `json:"stocks_count"`
+	ReactionsCount int `json:"reactions_count"`
+	CommentsCount  int `json:"comments_count"`
+	// PageViewsCount is available only for the articles of the authenticated user
+	// and only from GET /items/:id.
+	PageViewsCount int `json:"page_views_count"`
 }
 
 // Tag is a structure that represents the QiitaAPI.
@@ -39,18 +55,37 @@ type User struct {
 	Name string `json:"name"`
 }
 
+// Comment is a structure that represents the QiitaAPI.
+//
+// See also https://qiita.com/api/v2/docs#%E3%82%B3%E3%83%A1%E3%83%B3%E3%83%88.
+type Comment struct {
+	ID           string    `json:"id"`
+	Body         string    `json:"body"`
+	RenderedBody string    `json:"rendered_body"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	User         User      `json:"user"`
+}
+
 // PostItem is a structure that represents the Qiita API
 // required to post an Article to Qiita.
 //
 // See also https://qiita.com/api/v2/docs#post-apiv2items.
 type PostItem struct {
-	Body     string `json:"body"`
-	Private  bool   `json:"private"`
-	Tags     []*Tag `json:"tags"`
-	Title    string `json:"title"`
-	ID       string
-	URL      string
-	FilePath string
+	Body    string `json:"body"`
+	Private bool   `json:"private"`
+	Tags    []*Tag `json:"tags"`
+	Title   string `json:"title"`
+	Slide   bool   `json:"slide"`
+	// OrganizationURLName is omitted when it is empty, so that the organization is left as it is.
+	OrganizationURLName string `json:"organization_url_name,omitempty"`
+	// Tweet is only available when the article is posted.
+	Tweet bool `json:"tweet,omitempty"`
+
+	// The following fields are used only locally and are not sent to Qiita.
+	ID       string `json:"-"`
+	URL      string `json:"-"`
+	FilePath string `json:"-"`
 }
 
 // PostItemResult is a structure that represents the response body
@@ -67,6 +102,9 @@ type PostItemResult struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	URL       string    `json:"url"`
 	User      User      `json:"user"`
+	Slide     bool      `json:"slide"`
+
+	OrganizationURLName string `json:"organization_url_name"`
 }
 
 func dateFormat(time time.Time) string {
@@ -103,3 +141,24 @@ func MarshalTag(tagString string) []*Tag {
 	}
 	return tags
 }
+
+// ParseItemID extracts the ID of an article from either the ID itself or the URL of the article.
+//
+// Both "https://qiita.com/<user>/items/<id>" and the URL of a private article
+// such as "https://qiita.com/<user>/private/<id>" are accepted.
+func ParseItemID(s string) (string, error) {
+	if itemIDReg.MatchString(s) {
+		return s, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("parse item url: %w", err)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if n := len(segments); n >= 2 && (segments[n-2] == "items" || segments[n-2] == "private") {
+		if id := segments[n-1]; itemIDReg.MatchString(id) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("%q is neither an item ID nor an item URL", s)
+}
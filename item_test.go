@@ -71,3 +71,60 @@ func TestDateFormat(t *testing.T) {
 		t.Errorf("dateFormat() = %v, want %v", got, want)
 	}
 }
+
+func TestParseItemID(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "id",
+			s:    "c686397e4a0f4f11683d",
+			want: "c686397e4a0f4f11683d",
+		},
+		{
+			name: "public_url",
+			s:    "https://qiita.com/tutuz/items/c686397e4a0f4f11683d",
+			want: "c686397e4a0f4f11683d",
+		},
+		{
+			name: "private_url",
+			s:    "https://qiita.com/tutuz/private/c686397e4a0f4f11683d",
+			want: "c686397e4a0f4f11683d",
+		},
+		{
+			name: "posted_private_url",
+			s:    "https://qiita.com/tutuz/items/private/c686397e4a0f4f11683d",
+			want: "c686397e4a0f4f11683d",
+		},
+		{
+			name: "url_with_fragment",
+			s:    "https://qiita.com/tutuz/items/c686397e4a0f4f11683d#comments",
+			want: "c686397e4a0f4f11683d",
+		},
+		{
+			name:    "user_url",
+			s:       "https://qiita.com/tutuz",
+			wantErr: true,
+		},
+		{
+			name:    "invalid_id",
+			s:       "hoge",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseItemID(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseItemID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseItemID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package qiisync
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severities of the lint rules.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityOff     = "off"
+)
+
+// The lint rules and their default severities.
+var defaultLintRules = map[string]string{
+	"code-fence":    SeverityError,
+	"note-type":     SeverityError,
+	"relative-link": SeverityError,
+	"heading-level": SeverityWarning,
+	"image-alt":     SeverityWarning,
+	"title-length":  SeverityError,
+	"tag-count":     SeverityError,
+	"empty-body":    SeverityError,
+}
+
+const (
+	defaultMaxTitleLength = 255
+	maxTags               = 5
+)
+
+var (
+	headingReg   = regexp.MustCompile(`^(#{1,6})(\s|$)`)
+	noteReg      = regexp.MustCompile(`^:::\s*note(?:\s+(\S+))?`)
+	linkReg      = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^\s)>]+)`)
+	htmlImgReg   = regexp.MustCompile(`(?i)<img\s[^>]*>`)
+	htmlAltReg   = regexp.MustCompile(`(?i)\salt\s*=\s*["'][^"']+["']`)
+	urlSchemeReg = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// LintIssue is a problem found in an article by the linter.
+type LintIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i *LintIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s (%s)", i.File, i.Severity, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s (%s)", i.File, i.Line, i.Severity, i.Message, i.Rule)
+}
+
+// lintSeverity returns the severity of the rule configured in the [lint.rules] section.
+func (c *Config) lintSeverity(rule string) string {
+	if s, ok := c.Lint.Rules[rule]; ok {
+		return s
+	}
+	return defaultLintRules[rule]
+}
+
+// validate returns an error naming the first unknown rule or invalid severity in the
+// [lint.rules] section, so that a typo never disables a rule silently.
+func (c *lintConfig) validate() error {
+	rules := make([]string, 0, len(c.Rules))
+	for rule := range c.Rules {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		if _, ok := defaultLintRules[rule]; !ok {
+			return fmt.Errorf("lint.rules.%s: unknown lint rule", rule)
+		}
+		switch s := c.Rules[rule]; s {
+		case SeverityError, SeverityWarning, SeverityOff:
+		default:
+			return fmt.Errorf("lint.rules.%s: invalid severity %q. use %s, %s or %s", rule, s, SeverityError, SeverityWarning, SeverityOff)
+		}
+	}
+	return nil
+}
+
+func (c *Config) maxTitleLength() int {
+	if c.Lint.MaxTitleLength > 0 {
+		return c.Lint.MaxTitleLength
+	}
+	return defaultMaxTitleLength
+}
+
+// Lint checks the article for the constructs that Qiita rejects or renders unexpectedly.
+func (b *Broker) Lint(a *Article) []*LintIssue {
+	var issues []*LintIssue
+	offset := bodyLineOffset(a.FilePath, a.Item.Body)
+	report := func(rule string, line int, format string, args ...interface{}) {
+		severity := b.lintSeverity(rule)
+		if severity == SeverityOff {
+			return
+		}
+		if line > 0 {
+			line += offset
+		}
+		issues = append(issues, &LintIssue{
+			Rule:     rule,
+			Severity: severity,
+			File:     a.FilePath,
+			Line:     line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if n := utf8.RuneCountInString(a.Title); n > b.maxTitleLength() {
+		report("title-length", 0, "title is %d characters, longer than %d", n, b.maxTitleLength())
+	}
+	if n := countTags(a.Tags); n == 0 {
+		report("tag-count", 0, "at least one tag is required")
+	} else if n > maxTags {
+		report("tag-count", 0, "%d tags are specified, but up to %d tags are allowed", n, maxTags)
+	}
+	if strings.TrimSpace(a.Item.Body) == "" {
+		report("empty-body", 0, "body is empty")
+		return issues
+	}
+
+	var (
+		fence     codeFence
+		fenceLine int
+		prevLevel int
+	)
+	for i, line := range strings.Split(a.Item.Body, "\n") {
+		n := i + 1
+		if fence.inside() {
+			fence.closes(line)
+			continue
+		}
+		if fence.opens(line) {
+			fenceLine = n
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+
+		if m := headingReg.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			if prevLevel > 0 && level > prevLevel+1 {
+				report("heading-level", n, "heading level skips from h%d to h%d", prevLevel, level)
+			}
+			prevLevel = level
+		}
+		if m := noteReg.FindStringSubmatch(trimmed); m != nil && m[1] != "" && !noteTypes[m[1]] {
+			report("note-type", n, "unknown note type %q, it must be one of info, warn and alert", m[1])
+		}
+		for _, m := range linkReg.FindAllStringSubmatch(line, -1) {
+			isImage, alt, link := m[1] == "!", m[2], m[3]
+			if isImage && strings.TrimSpace(alt) == "" {
+				report("image-alt", n, "image %s has no alt text", link)
+			}
+			if target, ok := relativeLinkTarget(a.FilePath, link); ok {
+				if _, err := os.Stat(target); err != nil {
+					report("relative-link", n, "%s does not exist", link)
+				}
+			}
+		}
+		for _, tag := range htmlImgReg.FindAllString(line, -1) {
+			if !htmlAltReg.MatchString(tag) {
+				report("image-alt", n, "%s has no alt text", tag)
+			}
+		}
+	}
+	if fence.inside() {
+		report("code-fence", fenceLine, "code block is not closed")
+	}
+	return issues
+}
+
+// relativeLinkTarget returns the path in the local filesystem that link refers to,
+// if link is relative to the article.
+func relativeLinkTarget(articlePath, link string) (string, bool) {
+	if articlePath == "" || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "//") || urlSchemeReg.MatchString(link) {
+		return "", false
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	p := filepath.FromSlash(u.Path)
+	if filepath.IsAbs(p) {
+		// An absolute path is a path on Qiita, such as "/tags/go".
+		return "", false
+	}
+	return filepath.Join(filepath.Dir(articlePath), p), true
+}
+
+func countTags(tagString string) int {
+	n := 0
+	for _, t := range MarshalTag(tagString) {
+		if strings.TrimSpace(t.Name) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// LintErrors returns an error summarizing the issues whose severity is error.
+// Warnings are only logged.
+func LintErrors(issues []*LintIssue) error {
+	var errs []string
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			errs = append(errs, "  "+i.String())
+			continue
+		}
+		Logf("lint", "%s", i)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("article has %d lint errors:\n%s", len(errs), strings.Join(errs, "\n"))
+}
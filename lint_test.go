@@ -0,0 +1,127 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLint(t *testing.T) {
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+		return
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "exists.png"), []byte("png"), 0644); err != nil {
+		t.Errorf("write image: %v", err)
+		return
+	}
+	path := filepath.Join(tempDir, "test.md")
+
+	tests := []struct {
+		name   string
+		header string
+		body   string
+		rules  map[string]string
+		want   []*LintIssue
+	}{
+		{
+			name:   "valid",
+			header: "Title: test\nTags: Go\n",
+			body:   "## はじめに\n\n![図](./exists.png)\n[Qiita](https://qiita.com)\n[タグ](/tags/go)\n\n```go\n# not heading\n```\n\n:::note warn\n注意\n:::\n",
+		},
+		{
+			name:   "body",
+			header: "Title: test\nTags: Go\n",
+			body:   "# はじめに\n\n### 詳細\n\n![](./missing.png)\n<img src=\"https://example.com/a.png\">\n:::note danger\n:::\n\n```go\nfunc main() {}\n",
+			want: []*LintIssue{
+				{Rule: "heading-level", Severity: SeverityWarning, File: path, Line: 8, Message: "heading level skips from h1 to h3"},
+				{Rule: "image-alt", Severity: SeverityWarning, File: path, Line: 10, Message: "image ./missing.png has no alt text"},
+				{Rule: "relative-link", Severity: SeverityError, File: path, Line: 10, Message: "./missing.png does not exist"},
+				{Rule: "image-alt", Severity: SeverityWarning, File: path, Line: 11, Message: `<img src="https://example.com/a.png"> has no alt text`},
+				{Rule: "note-type", Severity: SeverityError, File: path, Line: 12, Message: `unknown note type "danger", it must be one of info, warn and alert`},
+				{Rule: "code-fence", Severity: SeverityError, File: path, Line: 15, Message: "code block is not closed"},
+			},
+		},
+		{
+			name:   "header",
+			header: "Title: " + strings.Repeat("あ", 256) + "\nTags: a,b,c,d,e,f\n",
+			body:   "\n",
+			want: []*LintIssue{
+				{Rule: "title-length", Severity: SeverityError, File: path, Message: "title is 256 characters, longer than 255"},
+				{Rule: "tag-count", Severity: SeverityError, File: path, Message: "6 tags are specified, but up to 5 tags are allowed"},
+				{Rule: "empty-body", Severity: SeverityError, File: path, Message: "body is empty"},
+			},
+		},
+		{
+			name:   "configured",
+			header: "Title: test\nTags: \"\"\n",
+			body:   "# はじめに\n\n### 詳細\n",
+			rules:  map[string]string{"heading-level": SeverityError, "tag-count": SeverityOff},
+			want: []*LintIssue{
+				{Rule: "heading-level", Severity: SeverityError, File: path, Line: 8, Message: "heading level skips from h1 to h3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(path, []byte("---\n"+tt.header+"---\n\n"+tt.body), 0644); err != nil {
+				t.Errorf("write article: %v", err)
+				return
+			}
+			a, err := ArticleFromFile(path)
+			if err != nil {
+				t.Errorf("ArticleFromFile(): %v", err)
+				return
+			}
+
+			b := &Broker{Config: &Config{Lint: lintConfig{Rules: tt.rules}}}
+			got := b.Lint(a)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Lint() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLintErrors(t *testing.T) {
+	if err := LintErrors([]*LintIssue{{Rule: "image-alt", Severity: SeverityWarning}}); err != nil {
+		t.Errorf("LintErrors() = %v, want nil for warnings", err)
+	}
+	if err := LintErrors([]*LintIssue{{Rule: "empty-body", Severity: SeverityError}}); err == nil {
+		t.Errorf("expected error occurred if there are lint errors")
+	}
+}
+
+func Test_lintConfig_validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules map[string]string
+		want  string
+	}{
+		{name: "valid", rules: map[string]string{"code-fence": "warning", "image-alt": "off"}},
+		{name: "unknown_rule", rules: map[string]string{"code-fence": "error", "image-alts": "off"}, want: "lint.rules.image-alts: unknown lint rule"},
+		{name: "invalid_severity", rules: map[string]string{"image-alt": "warn"}, want: `lint.rules.image-alt: invalid severity "warn". use error, warning or off`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&lintConfig{Rules: tt.rules}).validate()
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package qiisync
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// ArticleSummary is the metadata of an article shown by list.
+type ArticleSummary struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Tags    string `json:"tags"`
+	Private bool   `json:"private"`
+	// CreatedAt of a local article is taken from the date directory it is stored in,
+	// and is zero if the directory is not named after a date.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// URL is empty for local articles.
+	URL string `json:"url"`
+	// Path is the local file of the article, or empty if the remote article has not been pulled.
+	Path string `json:"path"`
+}
+
+var listColumns = []string{"id", "title", "tags", "private", "created_at", "updated_at", "url", "path"}
+
+func (s *ArticleSummary) record() []string {
+	return []string{
+		s.ID,
+		s.Title,
+		s.Tags,
+		strconv.FormatBool(s.Private),
+		formatTime(s.CreatedAt, time.RFC3339),
+		formatTime(s.UpdatedAt, time.RFC3339),
+		s.URL,
+		s.Path,
+	}
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// localCreatedAt returns the date of the directory the local article is stored in,
+// or the zero value of time.Time if the directory is not named after a date.
+func localCreatedAt(path string) time.Time {
+	t, err := time.ParseInLocation(defaultDataFormat, filepath.Base(filepath.Dir(path)), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// ListArticles returns the summaries of the articles that match filter.
+// The local articles are listed offline from base_dir. If remote is true,
+// the articles on Qiita are listed with the local files they are stored in.
+func (b *Broker) ListArticles(remote bool, filter *ArticleFilter) ([]*ArticleSummary, error) {
+	localArticles, err := b.FetchLocalArticles()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []*Article
+	if remote {
+		articles, err = b.FetchRemoteArticles()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, a := range localArticles {
+			articles = append(articles, a)
+		}
+	}
+
+	var summaries []*ArticleSummary
+	for _, a := range FilterArticles(articles, filter) {
+		s := &ArticleSummary{
+			ID:        a.ID,
+			Title:     a.Title,
+			Tags:      a.Tags,
+			Private:   a.Private,
+			CreatedAt: a.Item.CreatedAt,
+			UpdatedAt: a.Item.UpdatedAt,
+			URL:       a.Item.URL,
+			Path:      a.FilePath,
+		}
+		if remote {
+			if la, ok := localArticles[a.ID]; ok {
+				s.Path = la.FilePath
+			}
+		} else {
+			s.CreatedAt = localCreatedAt(a.FilePath)
+		}
+		summaries = append(summaries, s)
+	}
+	// The order of the local articles is not stable, since they are kept in a map.
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+	return summaries, nil
+}
+
+// SortArticleSummaries sorts the summaries by key in descending order, or ascending order if asc is true.
+// The key is one of id, title, created_at, updated_at and path.
+func SortArticleSummaries(summaries []*ArticleSummary, key string, asc bool) error {
+	var less func(a, b *ArticleSummary) bool
+	switch key {
+	case "id":
+		less = func(a, b *ArticleSummary) bool { return a.ID < b.ID }
+	case "title":
+		less = func(a, b *ArticleSummary) bool { return a.Title < b.Title }
+	case "created_at":
+		less = func(a, b *ArticleSummary) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updated_at":
+		less = func(a, b *ArticleSummary) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	case "path":
+		less = func(a, b *ArticleSummary) bool { return a.Path < b.Path }
+	default:
+		return fmt.Errorf("unknown sort key: %s", key)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if asc {
+			return less(summaries[i], summaries[j])
+		}
+		return less(summaries[j], summaries[i])
+	})
+	return nil
+}
+
+// WriteArticleSummaries writes the summaries to w in format, "table", "csv", "json"
+// or a Go template like "{{.ID}} {{.Title}}" executed for each article.
+func WriteArticleSummaries(w io.Writer, summaries []*ArticleSummary, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPRIVATE\tCREATED\tUPDATED\tTAGS\tTITLE\tPATH")
+		for _, s := range summaries {
+			fmt.Fprintf(tw, "%s\t%t\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.Private,
+				formatTime(s.CreatedAt, "2006-01-02"), formatTime(s.UpdatedAt, "2006-01-02"), s.Tags, s.Title, s.Path)
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(listColumns); err != nil {
+			return err
+		}
+		for _, s := range summaries {
+			if err := cw.Write(s.record()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if summaries == nil {
+			summaries = []*ArticleSummary{}
+		}
+		return enc.Encode(summaries)
+	}
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		if err := tmpl.Execute(w, s); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
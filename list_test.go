@@ -0,0 +1,278 @@
+package qiisync
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListArticles(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	files := map[string]string{
+		"20200423/go.md":     "---\nID: c686397e4a0f4f11683d\nTitle: はじめてのGo\nTags: Go:1.14\nPrivate: false\n---\n# Go\n",
+		"drafts/python.md":   "---\nID: 1234567890abcdefghij\nTitle: はじめてのPython\nTags: Python\nPrivate: true\n---\n# Python\n",
+		"20200501/new.md":    "---\nTitle: 新しい記事\nTags: Go\n---\n# New\n",
+		"20200501/notes.txt": "notes\n",
+	}
+	updatedAt := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	for name, content := range files {
+		p := filepath.Join(b.baseDir(), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, updatedAt, updatedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		w.Header().Set("Total-Count", "2")
+		if r.FormValue("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[
+			{
+				"id": "c686397e4a0f4f11683d",
+				"title": "はじめてのGo",
+				"tags": [{"name": "Go", "versions": ["1.14"]}],
+				"private": false,
+				"created_at": "2020-04-23T05:41:35+00:00",
+				"updated_at": "2020-04-24T05:41:35+00:00",
+				"url": "https://qiita.com/d-tsuji/items/c686397e4a0f4f11683d"
+			},
+			{
+				"id": "abcdefghij1234567890",
+				"title": "Remote only",
+				"tags": [{"name": "Rust", "versions": []}],
+				"private": false,
+				"created_at": "2020-03-01T00:00:00+00:00",
+				"updated_at": "2020-03-01T00:00:00+00:00",
+				"url": "https://qiita.com/d-tsuji/items/abcdefghij1234567890"
+			}
+		]`)
+	})
+
+	tests := []struct {
+		name   string
+		remote bool
+		filter *ArticleFilter
+		want   []*ArticleSummary
+	}{
+		{
+			name:   "local",
+			filter: &ArticleFilter{},
+			want: []*ArticleSummary{
+				{
+					ID:        "1234567890abcdefghij",
+					Title:     "はじめてのPython",
+					Tags:      "Python",
+					Private:   true,
+					UpdatedAt: updatedAt,
+					Path:      filepath.Join(b.baseDir(), "drafts", "python.md"),
+				},
+				{
+					ID:        "c686397e4a0f4f11683d",
+					Title:     "はじめてのGo",
+					Tags:      "Go:1.14",
+					CreatedAt: time.Date(2020, 4, 23, 0, 0, 0, 0, time.Local),
+					UpdatedAt: updatedAt,
+					Path:      filepath.Join(b.baseDir(), "20200423", "go.md"),
+				},
+			},
+		},
+		{
+			name:   "local_private_only",
+			filter: &ArticleFilter{PrivateOnly: true},
+			want: []*ArticleSummary{
+				{
+					ID:        "1234567890abcdefghij",
+					Title:     "はじめてのPython",
+					Tags:      "Python",
+					Private:   true,
+					UpdatedAt: updatedAt,
+					Path:      filepath.Join(b.baseDir(), "drafts", "python.md"),
+				},
+			},
+		},
+		{
+			name:   "remote",
+			remote: true,
+			filter: &ArticleFilter{},
+			want: []*ArticleSummary{
+				{
+					ID:        "abcdefghij1234567890",
+					Title:     "Remote only",
+					Tags:      "Rust",
+					CreatedAt: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+					UpdatedAt: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+					URL:       "https://qiita.com/d-tsuji/items/abcdefghij1234567890",
+				},
+				{
+					ID:        "c686397e4a0f4f11683d",
+					Title:     "はじめてのGo",
+					Tags:      "Go:1.14",
+					CreatedAt: time.Date(2020, 4, 23, 5, 41, 35, 0, time.UTC),
+					UpdatedAt: time.Date(2020, 4, 24, 5, 41, 35, 0, time.UTC),
+					URL:       "https://qiita.com/d-tsuji/items/c686397e4a0f4f11683d",
+					Path:      filepath.Join(b.baseDir(), "20200423", "go.md"),
+				},
+			},
+		},
+		{
+			name:   "remote_tag",
+			remote: true,
+			filter: &ArticleFilter{Tags: []string{"rust"}},
+			want: []*ArticleSummary{
+				{
+					ID:        "abcdefghij1234567890",
+					Title:     "Remote only",
+					Tags:      "Rust",
+					CreatedAt: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+					UpdatedAt: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+					URL:       "https://qiita.com/d-tsuji/items/abcdefghij1234567890",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.ListArticles(tt.remote, tt.filter)
+			if err != nil {
+				t.Errorf("ListArticles(): %v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ListArticles() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func testSummaries() []*ArticleSummary {
+	return []*ArticleSummary{
+		{ID: "1111", Title: "B", Tags: "Go", CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2000, 1, 5, 0, 0, 0, 0, time.UTC), Path: "b.md"},
+		{ID: "2222", Title: "A", Tags: "Go,Python", Private: true, CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2000, 1, 6, 0, 0, 0, 0, time.UTC), Path: "a.md"},
+		{ID: "3333", Title: "C", Tags: "Rust", CreatedAt: time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2000, 1, 4, 0, 0, 0, 0, time.UTC), Path: "c.md"},
+	}
+}
+
+func TestSortArticleSummaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		asc     bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "updated_at", key: "updated_at", want: []string{"2222", "1111", "3333"}},
+		{name: "created_at asc", key: "created_at", asc: true, want: []string{"2222", "1111", "3333"}},
+		{name: "title asc", key: "title", asc: true, want: []string{"2222", "1111", "3333"}},
+		{name: "id", key: "id", want: []string{"3333", "2222", "1111"}},
+		{name: "path asc", key: "path", asc: true, want: []string{"2222", "1111", "3333"}},
+		{name: "unknown", key: "likes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries := testSummaries()
+			err := SortArticleSummaries(summaries, tt.key, tt.asc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SortArticleSummaries() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, s := range summaries {
+				got = append(got, s.ID)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SortArticleSummaries() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteArticleSummaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "csv",
+			format: "csv",
+			want: `id,title,tags,private,created_at,updated_at,url,path
+1111,B,Go,false,2000-01-02T00:00:00Z,2000-01-05T00:00:00Z,,b.md
+`,
+		},
+		{
+			name:   "json",
+			format: "json",
+			want: `[
+  {
+    "id": "1111",
+    "title": "B",
+    "tags": "Go",
+    "private": false,
+    "created_at": "2000-01-02T00:00:00Z",
+    "updated_at": "2000-01-05T00:00:00Z",
+    "url": "",
+    "path": "b.md"
+  }
+]
+`,
+		},
+		{
+			name:   "table",
+			format: "table",
+			want: `ID    PRIVATE  CREATED     UPDATED     TAGS  TITLE  PATH
+1111  false    2000-01-02  2000-01-05  Go    B      b.md
+`,
+		},
+		{
+			name:   "template",
+			format: `{{.ID}} {{.Title}} {{.UpdatedAt.Format "2006-01-02"}}`,
+			want:   "1111 B 2000-01-05\n",
+		},
+		{name: "invalid_template", format: "{{.ID", wantErr: true},
+		{name: "unknown_field", format: "{{.Likes}}", wantErr: true},
+		{name: "unknown", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteArticleSummaries(&buf, testSummaries()[:1], tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteArticleSummaries() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("WriteArticleSummaries() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
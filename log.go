@@ -8,11 +8,22 @@ import (
 
 var logger = &colorine.Logger{
 	Prefixes: colorine.Prefixes{
-		"http":  colorine.Verbose,
-		"store": colorine.Info,
-		"post":  colorine.Info,
-		"error": colorine.Error,
-		"":      colorine.Verbose,
+		"http":    colorine.Verbose,
+		"store":   colorine.Info,
+		"backup":  colorine.Info,
+		"post":    colorine.Info,
+		"image":   colorine.Info,
+		"preview": colorine.Info,
+		"lint":    colorine.Warn,
+		"secret":  colorine.Warn,
+		"tag":     colorine.Warn,
+		"skip":    colorine.Verbose,
+		"publish": colorine.Notice,
+		"watch":   colorine.Notice,
+		"git":     colorine.Info,
+		"import":  colorine.Warn,
+		"error":   colorine.Error,
+		"":        colorine.Verbose,
 	},
 }
 
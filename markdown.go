@@ -0,0 +1,209 @@
+package qiisync
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// The placeholders consist only of alphanumerics so that the Markdown renderer leaves them as they are.
+const (
+	notePlaceholderPrefix = "QIISYNCNOTE"
+	mathPlaceholderPrefix = "QIISYNCMATH"
+)
+
+var (
+	noteStartReg       = regexp.MustCompile(`^:::\s*note(?:\s+(\w+))?\s*$`)
+	notePlaceholderReg = regexp.MustCompile(`<p>` + notePlaceholderPrefix + `(START(\w+)|END)</p>`)
+	mathPlaceholderReg = regexp.MustCompile(mathPlaceholderPrefix + `(\d+)X`)
+	codeFrameReg       = regexp.MustCompile(`(?s)<pre><code class="language-([^":]*):([^"]+)">(.*?)</code></pre>`)
+	mathCodeReg        = regexp.MustCompile(`(?s)<pre><code class="language-math">(.*?)</code></pre>`)
+	taskListReg        = regexp.MustCompile(`<li>(<p>)?\[([ xX])\]`)
+
+	// noteTypes are the types of :::note blocks that Qiita supports.
+	noteTypes = map[string]bool{"info": true, "warn": true, "alert": true}
+)
+
+// codeFence is the state of the fenced code blocks while the lines of Markdown are scanned in order.
+// A code block opens with three or more backquotes or tildes and closes only with a line of the
+// same character at least as long, so that a longer fence can hold a shorter one as a sample.
+type codeFence struct {
+	// marker is the fence that opened the current code block, or empty outside code blocks.
+	marker string
+}
+
+// inside reports whether the scanned lines are in a code block.
+func (f *codeFence) inside() bool {
+	return f.marker != ""
+}
+
+// opens reports whether line opens a code block, and enters the code block if so.
+// It is called for the lines outside code blocks.
+func (f *codeFence) opens(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+		return false
+	}
+	f.marker = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+	return true
+}
+
+// closes reports whether line closes the current code block, and leaves the code block if so.
+// It is called for the lines in code blocks.
+func (f *codeFence) closes(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < len(f.marker) || strings.Trim(trimmed, f.marker[:1]) != "" {
+		return false
+	}
+	f.marker = ""
+	return true
+}
+
+// info returns the info string of line that opened the current code block, such as "go:main.go".
+func (f *codeFence) info(line string) string {
+	return strings.TrimSpace(strings.TrimSpace(line)[len(f.marker):])
+}
+
+// scan updates the state with line, and reports whether line is outside code blocks and not a fence.
+func (f *codeFence) scan(line string) bool {
+	if f.inside() {
+		f.closes(line)
+		return false
+	}
+	return !f.opens(line)
+}
+
+// forEachLine calls fn with the 1-based line number for each line of body outside code blocks.
+func forEachLine(body string, fn func(n int, line string)) {
+	var fence codeFence
+	for i, line := range strings.Split(body, "\n") {
+		if fence.scan(line) {
+			fn(i+1, line)
+		}
+	}
+}
+
+// RenderMarkdown renders the body of an article as Qiita Markdown in HTML.
+//
+// In addition to the Markdown supported by the renderer, it handles the following notations of Qiita:
+// code blocks with "lang:filename", ":::note" blocks, math with "$" and "$$" and task lists.
+// Math is left to MathJax on the page that embeds the result.
+func RenderMarkdown(body string) string {
+	var math []string
+	src := preprocessMarkdown(body, &math)
+
+	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+		Flags: blackfriday.UseXHTML | blackfriday.FootnoteReturnLinks,
+	})
+	extensions := blackfriday.CommonExtensions | blackfriday.Footnotes | blackfriday.HardLineBreak
+	out := string(blackfriday.Run([]byte(src), blackfriday.WithRenderer(renderer), blackfriday.WithExtensions(extensions)))
+
+	// HardLineBreak leaves a line break at the end of list items.
+	out = strings.ReplaceAll(out, "<br />\n</li>", "\n</li>")
+	out = notePlaceholderReg.ReplaceAllStringFunc(out, func(s string) string {
+		m := notePlaceholderReg.FindStringSubmatch(s)
+		if m[1] == "END" {
+			return `</div>`
+		}
+		return fmt.Sprintf(`<div class="note %s">`, m[2])
+	})
+	out = codeFrameReg.ReplaceAllString(out, `<div class="code-frame"><div class="code-lang">$2</div><pre><code class="language-$1">$3</code></pre></div>`)
+	out = mathCodeReg.ReplaceAllString(out, `<div class="math">$$$$$1$$$$</div>`)
+	out = taskListReg.ReplaceAllStringFunc(out, func(s string) string {
+		m := taskListReg.FindStringSubmatch(s)
+		checked := ""
+		if m[2] != " " {
+			checked = ` checked="checked"`
+		}
+		return fmt.Sprintf(`<li class="task-list-item">%s<input type="checkbox" disabled="disabled"%s />`, m[1], checked)
+	})
+	out = mathPlaceholderReg.ReplaceAllStringFunc(out, func(s string) string {
+		i, _ := strconv.Atoi(mathPlaceholderReg.FindStringSubmatch(s)[1])
+		return html.EscapeString(math[i])
+	})
+	return out
+}
+
+// preprocessMarkdown replaces the notations the Markdown renderer does not know with placeholders.
+// The math expressions are stored in math so that the renderer does not break them.
+func preprocessMarkdown(body string, math *[]string) string {
+	placeholder := func(expr string) string {
+		*math = append(*math, expr)
+		return fmt.Sprintf("%s%dX", mathPlaceholderPrefix, len(*math)-1)
+	}
+
+	var (
+		lines    []string
+		fence    codeFence
+		mathLine []string
+		inMath   bool
+	)
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence.inside():
+			fence.closes(line)
+		case inMath:
+			mathLine = append(mathLine, line)
+			if strings.HasSuffix(trimmed, "$$") {
+				inMath = false
+				line = placeholder(strings.Join(mathLine, "\n"))
+			} else {
+				continue
+			}
+		case fence.opens(line):
+		case strings.HasPrefix(trimmed, "$$"):
+			if len(trimmed) >= 4 && strings.HasSuffix(trimmed, "$$") {
+				line = placeholder(trimmed)
+				break
+			}
+			inMath = true
+			mathLine = []string{line}
+			continue
+		case noteStartReg.MatchString(trimmed):
+			typ := noteStartReg.FindStringSubmatch(trimmed)[1]
+			if !noteTypes[typ] {
+				typ = "info"
+			}
+			line = "\n" + notePlaceholderPrefix + "START" + typ + "\n"
+		case trimmed == ":::":
+			line = "\n" + notePlaceholderPrefix + "END\n"
+		default:
+			line = replaceInlineMath(line, placeholder)
+		}
+		lines = append(lines, line)
+	}
+	if inMath {
+		lines = append(lines, mathLine...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceInlineMath replaces "$...$" outside code spans in line with the result of replace.
+func replaceInlineMath(line string, replace func(expr string) string) string {
+	var sb strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '`':
+			inCode = !inCode
+		case c == '\\' && i+1 < len(line):
+			sb.WriteByte(c)
+			i++
+			c = line[i]
+		case c == '$' && !inCode:
+			if end := strings.IndexByte(line[i+1:], '$'); end > 0 && line[i+1] != ' ' {
+				sb.WriteString(replace(line[i : i+end+2]))
+				i += end + 1
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
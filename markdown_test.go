@@ -0,0 +1,117 @@
+package qiisync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "code_filename",
+			body: "```go:main.go\nfunc main() {}\n```\n",
+			want: []string{`<div class="code-frame"><div class="code-lang">main.go</div><pre><code class="language-go">func main() {}`},
+		},
+		{
+			name: "note",
+			body: ":::note warn\nこれは**注意**です\n:::\n",
+			want: []string{`<div class="note warn">`, `<strong>注意</strong>`, `</div>`},
+		},
+		{
+			name: "note_default",
+			body: ":::note\n情報\n:::\n",
+			want: []string{`<div class="note info">`},
+		},
+		{
+			name: "math",
+			body: "$$\na_1 + b_1\n$$\n\ninline $x_1 < y_1$ and `$a_1$`\n",
+			want: []string{"$$\na_1 + b_1\n$$", "$x_1 &lt; y_1$", "<code>$a_1$</code>"},
+		},
+		{
+			name: "task_list",
+			body: "- [ ] todo\n- [x] done\n",
+			want: []string{
+				`<li class="task-list-item"><input type="checkbox" disabled="disabled" /> todo`,
+				`<li class="task-list-item"><input type="checkbox" disabled="disabled" checked="checked" /> done`,
+			},
+		},
+		{
+			name: "table",
+			body: "| a | b |\n|---|---|\n| 1 | 2 |\n",
+			want: []string{"<table>", "<td>1</td>"},
+		},
+		{
+			name: "footnote",
+			body: "本文[^1]\n\n[^1]: 脚注\n",
+			want: []string{`<div class="footnotes">`, "脚注"},
+		},
+		{
+			name: "note_in_code",
+			body: "```\n:::note\n```\n",
+			want: []string{":::note"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderMarkdown(tt.body)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("RenderMarkdown() = %v, want to contain %v", got, w)
+				}
+			}
+		})
+	}
+}
+
+func Test_forEachLine(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []int
+	}{
+		{
+			name: "backquotes",
+			body: "a\n```go\nb\n```\nc",
+			want: []int{1, 5},
+		},
+		{
+			name: "tildes",
+			body: "a\n~~~\nb\n```\nc\n~~~\nd",
+			want: []int{1, 7},
+		},
+		{
+			// A longer fence holds a shorter one as a sample of Markdown.
+			name: "nested",
+			body: "a\n````md\n```go\nb\n```\n````\nc",
+			want: []int{1, 7},
+		},
+		{
+			// The closing fence has nothing but the fence characters.
+			name: "not_closed_by_info",
+			body: "a\n```\n```go\nb\n```\nc",
+			want: []int{1, 6},
+		},
+		{
+			name: "not_closed",
+			body: "a\n```\nb",
+			want: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			forEachLine(tt.body, func(n int, line string) {
+				got = append(got, n)
+			})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("forEachLine() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
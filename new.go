@@ -0,0 +1,154 @@
+package qiisync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Songmu/flextime"
+)
+
+const (
+	templateDirName   = "templates"
+	templateExtension = ".md"
+)
+
+// templateData is the data the templates of new articles are rendered with.
+type templateData struct {
+	Title string
+	// Date is the date the article is created on, such as "2020-05-12".
+	Date string
+}
+
+// templateDir returns the directory of the templates of new articles.
+func (b *Broker) templateDir() (string, error) {
+	if b.Local.TemplateDir != "" {
+		return b.Local.TemplateDir, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, templateDirName), nil
+}
+
+// Templates returns the names of the templates of new articles in alphabetical order.
+func (b *Broker) Templates() ([]string, error) {
+	dir, err := b.templateDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != templateExtension {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(fi.Name(), templateExtension))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// renderTemplate renders the template of name, which is a Markdown file with the optional header.
+func (b *Broker) renderTemplate(name string, data *templateData) (*ArticleHeader, string, error) {
+	if name != filepath.Base(name) {
+		return nil, "", fmt.Errorf("invalid template name: %s", name)
+	}
+	dir, err := b.templateDir()
+	if err != nil {
+		return nil, "", err
+	}
+	d, err := ioutil.ReadFile(filepath.Join(dir, name+templateExtension))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("template %q is not found in %s", name, dir)
+		}
+		return nil, "", err
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(d))
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, "", err
+	}
+	ah, body, err := parseArticle(buf.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("template %s: %w", name, err)
+	}
+	return ah, body, nil
+}
+
+// NewArticle creates the local file of a new private article titled title in base_dir.
+// The article is rendered from the template of name if name is not empty, and tags
+// take precedence over the tags in the header of the template. The file is never overwritten.
+func (b *Broker) NewArticle(title, name, tags string) (*Article, error) {
+	if strings.TrimSpace(title) == "" {
+		return nil, errors.New("title is required")
+	}
+	now := flextime.Now()
+
+	ah, body := &ArticleHeader{}, ""
+	if name != "" {
+		var err error
+		ah, body, err = b.renderTemplate(name, &templateData{Title: title, Date: now.Format("2006-01-02")})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// The article is kept private until it is ready, whatever the template says.
+	ah.ID, ah.Title, ah.Private = "", title, true
+	if tags != "" {
+		ah.Tags = tags
+	}
+
+	a := &Article{
+		ArticleHeader: ah,
+		Item:          &Item{Title: title, Body: body, CreatedAt: now},
+		FilePath:      b.newArticlePath(title, now),
+	}
+	if err := b.createArticle(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// newArticlePath returns the path of the new article titled title created at t.
+// The file is named after the title even in the id mode, since the article has no ID yet.
+func (b *Broker) newArticlePath(title string, t time.Time) string {
+	return filepath.Join(b.baseDir(), dateFormat(t), invalidCharacterReg.ReplaceAllString(title, "_")+defaultExtension)
+}
+
+// createArticle writes the new article to its FilePath. An existing file is never overwritten.
+func (b *Broker) createArticle(a *Article) error {
+	if _, err := os.Stat(a.FilePath); err == nil {
+		return fmt.Errorf("%s already exists", a.FilePath)
+	}
+	content, err := a.fullContent()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.FilePath), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(a.FilePath, []byte(content), 0644); err != nil {
+		return err
+	}
+	Logf("store", "%s", a.FilePath)
+	return nil
+}
@@ -0,0 +1,137 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewArticle(t *testing.T) {
+	b, _, _, teardown := setup()
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		teardown()
+		flextime.Restore()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	b.Local.TemplateDir = tempDir
+
+	templates := map[string]string{
+		"tutorial.md":     "---\nTags: Go\nPrivate: false\nSlide: true\n---\n# {{.Title}}\n\n{{.Date}} に書きました。\n",
+		"release-note.md": "## 変更点\n",
+		"broken.md":       "{{.Author}}\n",
+		"README.txt":      "not a template\n",
+	}
+	for name, content := range templates {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := b.Templates()
+	if err != nil {
+		t.Errorf("Templates(): %v", err)
+		return
+	}
+	if diff := cmp.Diff([]string{"broken", "release-note", "tutorial"}, names); diff != "" {
+		t.Errorf("Templates() mismatch (-want +got):\n%s", diff)
+	}
+
+	flextime.Fix(time.Date(2020, 5, 12, 9, 0, 0, 0, time.Local))
+	tests := []struct {
+		name     string
+		title    string
+		template string
+		tags     string
+		wantPath string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "blank",
+			title:    "はじめてのGo",
+			wantPath: "20200512/はじめてのGo.md",
+			want:     "---\nID: \"\"\nTitle: はじめてのGo\nTags: \"\"\nAuthor: \"\"\nPrivate: true\n---\n\n",
+		},
+		{
+			name:     "template",
+			title:    "Go: チュートリアル",
+			template: "tutorial",
+			wantPath: "20200512/Go_ チュートリアル.md",
+			want:     "---\nID: \"\"\nTitle: 'Go: チュートリアル'\nTags: Go\nAuthor: \"\"\nPrivate: true\nSlide: true\n---\n\n# Go: チュートリアル\n\n2020-05-12 に書きました。\n",
+		},
+		{
+			name:     "tags",
+			title:    "v1.0.0",
+			template: "release-note",
+			tags:     "Go:1.14,qiisync",
+			wantPath: "20200512/v1.0.0.md",
+			want:     "---\nID: \"\"\nTitle: v1.0.0\nTags: Go:1.14,qiisync\nAuthor: \"\"\nPrivate: true\n---\n\n## 変更点\n",
+		},
+		{
+			name:    "exists",
+			title:   "はじめてのGo",
+			wantErr: true,
+		},
+		{
+			name:     "template_not_found",
+			title:    "Example",
+			template: "no-such-template",
+			wantErr:  true,
+		},
+		{
+			name:     "template_error",
+			title:    "Example",
+			template: "broken",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid_template_name",
+			title:    "Example",
+			template: "../tutorial",
+			wantErr:  true,
+		},
+		{
+			name:    "empty_title",
+			title:   " ",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := b.NewArticle(tt.title, tt.template, tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewArticle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			wantPath := filepath.Join(b.baseDir(), filepath.FromSlash(tt.wantPath))
+			if a.FilePath != wantPath {
+				t.Errorf("NewArticle() created %s, want %s", a.FilePath, wantPath)
+			}
+			got, err := ioutil.ReadFile(wantPath)
+			if err != nil {
+				t.Errorf("read file: %v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("NewArticle() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package qiisync
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// previewTemplate is the page of the preview. It polls the modification time of the file
+// and reloads itself when the file is saved.
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 860px; margin: 2em auto; padding: 0 1em; font-family: -apple-system, "Helvetica Neue", "Hiragino Sans", Meiryo, sans-serif; line-height: 1.8; color: #333; }
+h1.title { font-size: 2em; margin-bottom: 0.2em; }
+.tags span { display: inline-block; margin-right: 0.5em; padding: 0 0.5em; background: #eee; border-radius: 3px; font-size: 0.85em; }
+.private { color: #fff; background: #888; padding: 0 0.5em; border-radius: 3px; font-size: 0.85em; }
+pre { background: #364549; color: #e3e3e3; padding: 1em; overflow: auto; }
+.code-frame .code-lang { display: inline-block; background: #777; color: #fff; padding: 0 0.5em; font-size: 0.85em; }
+.code-frame pre { margin-top: 0; }
+.note { padding: 0.5em 1em; margin: 1em 0; border-left: 4px solid; }
+.note.info { background: #f0f7fb; border-color: #5bc0de; }
+.note.warn { background: #fcf8e3; border-color: #f0ad4e; }
+.note.alert { background: #fbeeed; border-color: #d9534f; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.8em; }
+blockquote { color: #777; border-left: 4px solid #ddd; margin-left: 0; padding-left: 1em; }
+.task-list-item { list-style: none; }
+img { max-width: 100%; }
+.error { color: #d9534f; }
+</style>
+<script>
+window.MathJax = { tex: { inlineMath: [["$", "$"]], displayMath: [["$$", "$$"]] } };
+</script>
+<script async src="https://cdn.jsdelivr.net/npm/mathjax@3/es5/tex-chtml.js"></script>
+</head>
+<body>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<h1 class="title">{{.Title}}</h1>
+<div class="tags">{{if .Private}}<span class="private">限定共有</span>{{end}}{{range .Tags}}<span>{{.}}</span>{{end}}</div>
+<hr>
+{{.Body}}
+<script>
+(function () {
+  var modified = "{{.Modified}}";
+  setInterval(function () {
+    fetch("/_modified").then(function (r) { return r.text(); }).then(function (t) {
+      if (t !== modified) { location.reload(); }
+    }).catch(function () {});
+  }, 1000);
+})();
+</script>
+</body>
+</html>
+`))
+
+type previewPage struct {
+	Title    string
+	Tags     []string
+	Private  bool
+	Body     template.HTML
+	Modified string
+	Error    string
+}
+
+// NewPreviewHandler returns the handler that renders the article of path as Qiita Markdown.
+// The page reloads itself when the article is saved.
+func NewPreviewHandler(path string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_modified", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, modifiedString(path))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			// Serve the local images referred by the article.
+			http.FileServer(http.Dir(filepath.Dir(path))).ServeHTTP(w, r)
+			return
+		}
+		page := &previewPage{Modified: modifiedString(path)}
+		a, err := ArticleFromFile(path)
+		if err != nil {
+			page.Error = err.Error()
+		} else {
+			page.Title = a.Title
+			if page.Title == "" {
+				// A new article does not have a header yet.
+				page.Title = filepath.Base(path)
+			}
+			page.Private = a.Private
+			if a.Tags != "" {
+				for _, t := range MarshalTag(a.Tags) {
+					page.Tags = append(page.Tags, t.Name)
+				}
+			}
+			page.Body = template.HTML(RenderMarkdown(a.Item.Body))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := previewTemplate.Execute(w, page); err != nil {
+			Logf("error", "render preview: %v", err)
+		}
+	})
+	return mux
+}
+
+func modifiedString(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(fi.ModTime().UnixNano())
+}
@@ -0,0 +1,41 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	path := filepath.Join("testdata", "article", "20_test_article_posted.md")
+	server := httptest.NewServer(NewPreviewHandler(path))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Errorf("get preview: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	got := string(b)
+	for _, want := range []string{"<title>はじめてのGo</title>", "<span>Go</span>", "<h1>はじめに</h1>", `var modified = "`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("preview = %v, want to contain %v", got, want)
+		}
+	}
+
+	resp, err = http.Get(server.URL + "/_modified")
+	if err != nil {
+		t.Errorf("get modified: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	b, _ = ioutil.ReadAll(resp.Body)
+	if modified := string(b); modified == "" || !strings.Contains(got, modified) {
+		t.Errorf("modified = %q, want to be embedded in the preview", modified)
+	}
+}
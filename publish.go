@@ -0,0 +1,267 @@
+package qiisync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Songmu/flextime"
+)
+
+const publishRecordFileName = "published.json"
+
+// The layouts accepted in PublishAt. The time without the time zone is in the local time zone.
+var publishAtLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// publishRecord is the result of publishing an article by PublishDue.
+type publishRecord struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	PublishAt   string    `json:"publish_at"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// PublishResult is the result of publishing a due article.
+type PublishResult struct {
+	File string
+	ID   string
+	URL  string
+	Err  error
+}
+
+// PublishTime returns the time specified by PublishAt.
+// If PublishAt is empty, it returns the zero value of time.Time.
+func (h *ArticleHeader) PublishTime() (time.Time, error) {
+	if h.PublishAt == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range publishAtLayouts {
+		if t, err := time.ParseInLocation(layout, h.PublishAt, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid PublishAt: %s", h.PublishAt)
+}
+
+func (b *Broker) publishRecordPath() string {
+	return filepath.Join(b.stateDir(), publishRecordFileName)
+}
+
+// loadPublishRecords returns the records of the published articles keyed by the ID of the article,
+// so that renaming or moving the local file does not publish the article again.
+func (b *Broker) loadPublishRecords() (map[string]*publishRecord, error) {
+	records := make(map[string]*publishRecord)
+	d, err := ioutil.ReadFile(b.publishRecordPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(d, &records); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	return records, nil
+}
+
+func (b *Broker) savePublishRecords(records map[string]*publishRecord) error {
+	p := b.publishRecordPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	d, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p, d, 0644)
+}
+
+// DueArticles returns the local articles whose PublishAt has passed, in the order of PublishAt.
+// They are new articles or private articles on Qiita. The articles already published are excluded.
+func (b *Broker) DueArticles() ([]*Article, error) {
+	files, err := b.LocalArticleFiles()
+	if err != nil {
+		return nil, err
+	}
+	records, err := b.loadPublishRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	now := flextime.Now()
+	var (
+		due   []*Article
+		times = make(map[*Article]time.Time)
+	)
+	for _, f := range files {
+		a, err := ArticleFromFile(f)
+		if err != nil {
+			return nil, err
+		}
+		t, err := a.PublishTime()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		if t.IsZero() || t.After(now) {
+			continue
+		}
+		if a.ID != "" && !a.Private {
+			continue
+		}
+		if _, published := records[a.ID]; a.ID != "" && published {
+			continue
+		}
+		due = append(due, a)
+		times[a] = t
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return times[due[i]].Before(times[due[j]])
+	})
+	return due, nil
+}
+
+// PublishDue publishes the local articles whose PublishAt has passed.
+// A new article is posted publicly, and a private article on Qiita is made public
+// without confirmation, since PublishAt is the confirmation. The published articles
+// are recorded so that they are never published twice. An article that fails to be
+// published is reported in the result and retried next time.
+func (b *Broker) PublishDue() ([]*PublishResult, error) {
+	due, err := b.DueArticles()
+	if err != nil {
+		return nil, err
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	records, err := b.loadPublishRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	publish := b.Publish
+	b.Publish = true
+	defer func() { b.Publish = publish }()
+
+	results := make([]*PublishResult, len(due))
+	for i, a := range due {
+		r := &PublishResult{File: a.FilePath}
+		results[i] = r
+
+		var published *Article
+		if a.ID == "" {
+			published, r.Err = b.postArticle(&PostItem{
+				Body:                a.Item.Body,
+				Private:             false,
+				Tags:                MarshalTag(a.Tags),
+				Title:               a.Title,
+				Slide:               a.SlideOr(false),
+				OrganizationURLName: a.Organization,
+				Tweet:               a.Tweet,
+				FilePath:            a.FilePath,
+			})
+		} else {
+			published, r.Err = b.publishPrivateArticle(a)
+		}
+		if r.Err != nil {
+			Logf("error", "%s: %v", a.FilePath, r.Err)
+			continue
+		}
+		r.ID, r.URL = published.ID, published.Item.URL
+		Logf("publish", "%s ---> %s", a.FilePath, r.URL)
+
+		records[r.ID] = &publishRecord{
+			ID:          r.ID,
+			URL:         r.URL,
+			PublishAt:   a.PublishAt,
+			PublishedAt: flextime.Now(),
+		}
+		if err := b.savePublishRecords(records); err != nil {
+			return results, err
+		}
+		if a.ID == "" {
+			if err := b.removeDraft(a.FilePath, published.FilePath); err != nil {
+				return results, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// removeDraft removes the file of a new article that has been posted and stored at postedPath,
+// so that the draft without ID is not posted again. The draft is kept as a backup.
+func (b *Broker) removeDraft(draftPath, postedPath string) error {
+	if filepath.Clean(draftPath) == filepath.Clean(postedPath) {
+		return nil
+	}
+	if _, err := b.backup("", draftPath, nil); err != nil {
+		return fmt.Errorf("backup %s: %w", draftPath, err)
+	}
+	Logf("store", "remove %s", draftPath)
+	return os.Remove(draftPath)
+}
+
+// publishPrivateArticle makes the private article on Qiita public and
+// stores the published article in place of the local file.
+//
+// The local changes are published together if the local file is newer. Otherwise the article on
+// Qiita is published as it is, since PublishAt is the confirmation whatever the modified time is.
+func (b *Broker) publishPrivateArticle(a *Article) (*Article, error) {
+	ra, err := b.fetchRemoteArticle(a)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !ra.Private:
+		// It has been made public on Qiita already.
+	case a.Item.UpdatedAt.After(ra.Item.UpdatedAt):
+		header := *a.ArticleHeader
+		header.Private = false
+		updated, err := b.UploadFresh(&Article{ArticleHeader: &header, Item: a.Item, FilePath: a.FilePath})
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, fmt.Errorf("article on Qiita is updated while publishing. pull it first")
+		}
+	default:
+		// The article on Qiita is checked as any article made public is.
+		header := *ra.ArticleHeader
+		header.Private = false
+		if err := b.checkArticle(&Article{ArticleHeader: &header, Item: ra.Item, FilePath: a.FilePath}); err != nil {
+			return nil, err
+		}
+		if _, err := b.patchArticle(&PostItem{
+			Body:    ra.Item.Body,
+			Private: false,
+			Tags:    ra.Item.Tags,
+			Title:   ra.Item.Title,
+			Slide:   ra.Item.Slide,
+			ID:      ra.ID,
+			URL:     ra.Item.URL,
+		}); err != nil {
+			return nil, err
+		}
+		Logf("publish", "private ---> public %q %s", ra.Title, ra.Item.URL)
+	}
+
+	ra, err = b.fetchRemoteArticle(a)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.StoreFresh(map[string]*Article{a.ID: a}, ra); err != nil {
+		return nil, err
+	}
+	ra.FilePath = a.FilePath
+	return ra, nil
+}
@@ -0,0 +1,43 @@
+package qiisync
+
+// PushResult is the result of pushing a local article.
+type PushResult struct {
+	File string
+	ID   string
+	// Pushed reports whether the article was updated on Qiita, or would be with DryRun.
+	Pushed bool
+	Err    error
+}
+
+// PushArticles pushes the local articles in files to Qiita with UploadFresh.
+// Articles without ID are never posted. With DryRun they are linted instead,
+// so that the new articles are checked as well. An article that fails to be pushed
+// is reported in the result and the rest are pushed.
+func (b *Broker) PushArticles(files []string) []*PushResult {
+	results := make([]*PushResult, len(files))
+	for i, f := range files {
+		r := &PushResult{File: f}
+		results[i] = r
+
+		a, err := ArticleFromFile(f)
+		if err != nil {
+			r.Err = err
+			Logf("error", "%s: %v", f, err)
+			continue
+		}
+		r.ID = a.ID
+		if a.ID == "" {
+			if b.DryRun {
+				r.Err = LintErrors(b.Lint(a))
+			} else {
+				Logf("", "%s has not been posted yet. use post to post it", f)
+			}
+		} else {
+			r.Pushed, r.Err = b.UploadFresh(a)
+		}
+		if r.Err != nil {
+			Logf("error", "%s: %v", f, r.Err)
+		}
+	}
+	return results
+}
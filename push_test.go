@@ -0,0 +1,109 @@
+package qiisync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPushArticles(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantPatches int
+		want        []*PushResult
+	}{
+		{
+			name:        "push",
+			wantPatches: 1,
+			want: []*PushResult{
+				{File: "posted.md", ID: "c686397e4a0f4f11683d", Pushed: true},
+				{File: "draft.md"},
+				{File: "broken.md"},
+			},
+		},
+		{
+			name:   "dry_run",
+			dryRun: true,
+			want: []*PushResult{
+				{File: "posted.md", ID: "c686397e4a0f4f11683d", Pushed: true},
+				{File: "draft.md"},
+				{File: "broken.md"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, mux, _, teardown := setup()
+			t.Cleanup(func() {
+				teardown()
+				if err := os.RemoveAll(b.baseDir()); err != nil {
+					t.Errorf("remove tempDir: %v", err)
+				}
+			})
+			b.DryRun = tt.dryRun
+
+			if err := os.MkdirAll(b.baseDir(), 0755); err != nil {
+				t.Fatal(err)
+			}
+			contents := map[string]string{
+				"posted.md": "---\nID: c686397e4a0f4f11683d\nTitle: Example title\nTags: Go\n---\n# Example\n",
+				"draft.md":  "---\nTitle: Draft\nTags: Go\n---\n# Draft\n",
+				// The new article without tags is reported only with DryRun.
+				"broken.md": "---\nTitle: Broken\nTags: \n---\n# Broken\n",
+			}
+			var files []string
+			for _, r := range tt.want {
+				p := filepath.Join(b.baseDir(), r.File)
+				if err := ioutil.WriteFile(p, []byte(contents[r.File]), 0644); err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, p)
+			}
+
+			patches := 0
+			mux.HandleFunc("/api/v2/items", func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("article without ID is posted")
+			})
+			mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPatch {
+					patches++
+				}
+				fmt.Fprint(w, `
+					{
+						"body": "# Example",
+						"id": "c686397e4a0f4f11683d",
+						"private": false,
+						"title": "Example title",
+						"updated_at": "2020-04-23T05:41:35+00:00",
+						"url": "https://localhost/Test/items/c686397e4a0f4f11683d"
+					}
+`)
+			})
+
+			results := b.PushArticles(files)
+			if len(results) != len(tt.want) {
+				t.Fatalf("PushArticles() returned %d results, want %d", len(results), len(tt.want))
+			}
+			for i, r := range results {
+				wantErr := tt.dryRun && tt.want[i].File == "broken.md"
+				if (r.Err != nil) != wantErr {
+					t.Errorf("PushArticles() %s error = %v, wantErr %v", r.File, r.Err, wantErr)
+				}
+				r.Err = nil
+				r.File = filepath.Base(r.File)
+			}
+			if diff := cmp.Diff(tt.want, results); diff != "" {
+				t.Errorf("PushArticles() mismatch (-want +got):\n%s", diff)
+			}
+			if patches != tt.wantPatches {
+				t.Errorf("PushArticles() patched %d times, want %d", patches, tt.wantPatches)
+			}
+		})
+	}
+}
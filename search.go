@@ -0,0 +1,321 @@
+package qiisync
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	searchIndexFileName = "search.json"
+	// searchIndexVersion is increased when the way of indexing changes, so that the index is rebuilt.
+	searchIndexVersion = 1
+
+	// snippetRadius is the number of characters shown around the match in a snippet.
+	snippetRadius = 40
+)
+
+// The weights of the matches of a search term in each field of an article.
+// The matches in the body are counted up to maxBodyMatches.
+const (
+	titleMatchScore = 10
+	tagMatchScore   = 5
+	bodyMatchScore  = 1
+	maxBodyMatches  = 10
+)
+
+// searchIndex is the n-gram index of the local articles persisted in the state directory.
+// The n-grams are the characters and the pairs of adjacent characters of the normalized text,
+// so that Japanese text can be searched without a tokenizer.
+type searchIndex struct {
+	Version int `json:"version"`
+	// Docs are the indexed articles by the path relative to base_dir.
+	Docs map[string]*indexedArticle `json:"docs"`
+}
+
+type indexedArticle struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Tags  string `json:"tags"`
+	// ModTime and Size tell whether the file has changed since it was indexed.
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Grams   []string  `json:"grams"`
+}
+
+// SearchResult is a local article that matches the query.
+type SearchResult struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Path  string `json:"path"`
+	Score int    `json:"score"`
+	// Snippet is the part of the body around the first match.
+	Snippet string `json:"snippet"`
+}
+
+// normalizeText folds the text for searching. It lower-cases letters and converts
+// the full-width alphanumerics and spaces often used in Japanese text to the half-width ones.
+func normalizeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			r -= 0xfee0
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// ngrams returns the characters and the pairs of adjacent characters in the normalized text.
+// N-grams across whitespace are not included.
+func ngrams(s string) map[string]bool {
+	grams := make(map[string]bool)
+	for _, field := range strings.Fields(normalizeText(s)) {
+		rs := []rune(field)
+		for i := range rs {
+			grams[string(rs[i])] = true
+			if i+1 < len(rs) {
+				grams[string(rs[i:i+2])] = true
+			}
+		}
+	}
+	return grams
+}
+
+// queryGrams returns the n-grams that all the articles containing term have.
+func queryGrams(term string) []string {
+	rs := []rune(term)
+	if len(rs) == 1 {
+		return []string{term}
+	}
+	grams := make([]string, 0, len(rs)-1)
+	for i := 0; i+1 < len(rs); i++ {
+		grams = append(grams, string(rs[i:i+2]))
+	}
+	return grams
+}
+
+func (b *Broker) searchIndexPath() string {
+	return filepath.Join(b.stateDir(), searchIndexFileName)
+}
+
+func (b *Broker) loadSearchIndex() (*searchIndex, error) {
+	empty := &searchIndex{Version: searchIndexVersion, Docs: make(map[string]*indexedArticle)}
+	d, err := ioutil.ReadFile(b.searchIndexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return empty, nil
+		}
+		return nil, err
+	}
+	var idx searchIndex
+	// A broken or outdated index is rebuilt from scratch.
+	if err := json.Unmarshal(d, &idx); err != nil || idx.Version != searchIndexVersion || idx.Docs == nil {
+		return empty, nil
+	}
+	return &idx, nil
+}
+
+func (b *Broker) saveSearchIndex(idx *searchIndex) error {
+	d, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	p := b.searchIndexPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(p, d, 0644)
+}
+
+// UpdateSearchIndex updates the search index of the local articles incrementally.
+// Only the files changed since they were indexed are read again.
+func (b *Broker) UpdateSearchIndex() error {
+	_, err := b.updateSearchIndex()
+	return err
+}
+
+func (b *Broker) updateSearchIndex() (*searchIndex, error) {
+	idx, err := b.loadSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+	files, err := b.LocalArticleFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		key, err := relSlash(b.baseDir(), f)
+		if err != nil {
+			return nil, err
+		}
+		seen[key] = true
+
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		if doc, ok := idx.Docs[key]; ok && doc.ModTime.Equal(fi.ModTime()) && doc.Size == fi.Size() {
+			continue
+		}
+		a, err := ArticleFromFile(f)
+		if err != nil {
+			return nil, err
+		}
+		grams := ngrams(strings.Join([]string{a.Title, a.Tags, a.Item.Body}, "\n"))
+		doc := &indexedArticle{
+			ID:      a.ID,
+			Title:   a.Title,
+			Tags:    a.Tags,
+			ModTime: fi.ModTime(),
+			Size:    fi.Size(),
+			Grams:   make([]string, 0, len(grams)),
+		}
+		for g := range grams {
+			doc.Grams = append(doc.Grams, g)
+		}
+		sort.Strings(doc.Grams)
+		idx.Docs[key] = doc
+		changed = true
+	}
+	for key := range idx.Docs {
+		if !seen[key] {
+			delete(idx.Docs, key)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := b.saveSearchIndex(idx); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Search searches the local articles for all the whitespace separated terms in query,
+// and returns at most limit results from the highest score. The matches in the title
+// score higher than the ones in the tags, which score higher than the ones in the body.
+// If limit is 0, all the results are returned.
+func (b *Broker) Search(query string, limit int) ([]*SearchResult, error) {
+	terms := strings.Fields(normalizeText(query))
+	if len(terms) == 0 {
+		return nil, errors.New("query is empty")
+	}
+	idx, err := b.updateSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	postings := make(map[string][]string)
+	for key, doc := range idx.Docs {
+		for _, g := range doc.Grams {
+			postings[g] = append(postings[g], key)
+		}
+	}
+	// The candidates have all the n-grams of the terms,
+	// which are checked against the text since n-grams lose their order.
+	var candidates map[string]bool
+	for _, term := range terms {
+		for _, g := range queryGrams(term) {
+			next := make(map[string]bool)
+			for _, key := range postings[g] {
+				if candidates == nil || candidates[key] {
+					next[key] = true
+				}
+			}
+			candidates = next
+		}
+	}
+
+	var results []*SearchResult
+	for key := range candidates {
+		doc := idx.Docs[key]
+		path := filepath.Join(b.baseDir(), filepath.FromSlash(key))
+		a, err := ArticleFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if r := matchArticle(doc, a.Item.Body, terms); r != nil {
+			r.Path = path
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// matchArticle scores the article if it contains all the terms, or returns nil.
+func matchArticle(doc *indexedArticle, body string, terms []string) *SearchResult {
+	title, tags, normBody := normalizeText(doc.Title), normalizeText(doc.Tags), normalizeText(body)
+	r := &SearchResult{ID: doc.ID, Title: doc.Title}
+	snippetAt := -1
+	for _, term := range terms {
+		inTitle, inTags := strings.Contains(title, term), strings.Contains(tags, term)
+		n := strings.Count(normBody, term)
+		if !inTitle && !inTags && n == 0 {
+			return nil
+		}
+		if inTitle {
+			r.Score += titleMatchScore
+		}
+		if inTags {
+			r.Score += tagMatchScore
+		}
+		if n > maxBodyMatches {
+			n = maxBodyMatches
+		}
+		r.Score += n * bodyMatchScore
+		if i := strings.Index(normBody, term); i >= 0 && (snippetAt < 0 || i < snippetAt) {
+			snippetAt = i
+		}
+	}
+	r.Snippet = snippet(body, normBody, snippetAt)
+	return r
+}
+
+// snippet returns the part of body around the byte offset at in the normalized body,
+// or the beginning of body if at is negative. The normalization keeps the number of
+// characters, so the offset is converted through the character count.
+func snippet(body, normBody string, at int) string {
+	rs := []rune(body)
+	start := 0
+	if at > 0 {
+		start = utf8.RuneCountInString(normBody[:at]) - snippetRadius
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + 2*snippetRadius
+	if end > len(rs) {
+		end = len(rs)
+	}
+	s := strings.Join(strings.Fields(string(rs[start:end])), " ")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(rs) {
+		s += "..."
+	}
+	return s
+}
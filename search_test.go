@@ -0,0 +1,136 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_normalizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Go言語", want: "go言語"},
+		{in: "ＧＯ　１．１４", want: "go 1.14"},
+		{in: "はじめてのＰｙｔｈｏｎ", want: "はじめてのpython"},
+	}
+	for _, tt := range tests {
+		if got := normalizeText(tt.in); got != tt.want {
+			t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func Test_snippet(t *testing.T) {
+	body := strings.Repeat("あ", 50) + "\n関数\n" + strings.Repeat("い", 50)
+	got := snippet(body, normalizeText(body), strings.Index(body, "関数"))
+	want := "..." + strings.Repeat("あ", 39) + " 関数 " + strings.Repeat("い", 37) + "..."
+	if got != want {
+		t.Errorf("snippet() = %q, want %q", got, want)
+	}
+	if got := snippet("short body", "short body", -1); got != "short body" {
+		t.Errorf("snippet() = %q, want %q", got, "short body")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	write := func(name, content string) string {
+		p := filepath.Join(b.baseDir(), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	goPath := write("20200423/go.md", "---\nID: c686397e4a0f4f11683d\nTitle: Goの関数\nTags: Go:1.14\n---\n関数は func で定義します。\n")
+	pyPath := write("20200424/python.md", "---\nID: 1234567890abcdefghij\nTitle: はじめてのPython\nTags: Python\n---\nPython の関数は def で定義します。Go とは違います。\n")
+	draftPath := write("20200425/draft.md", "---\nTitle: 下書き\nTags: Rust\n---\nab bc の話\n")
+
+	search := func(query string) []string {
+		t.Helper()
+		results, err := b.Search(query, 0)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", query, err)
+		}
+		paths := []string{}
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "japanese", query: "関数", want: []string{goPath, pyPath}},
+		{name: "title_first", query: "python", want: []string{pyPath}},
+		{name: "full_width", query: "ＧＯ", want: []string{goPath, pyPath}},
+		{name: "all_terms", query: "関数 def", want: []string{pyPath}},
+		{name: "tag", query: "rust", want: []string{draftPath}},
+		{name: "single_character", query: "話", want: []string{draftPath}},
+		// The article has the n-grams of "abc" but not "abc" itself.
+		{name: "false_positive", query: "abc", want: []string{}},
+		{name: "no_match", query: "Haskell", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, search(tt.query)); diff != "" {
+				t.Errorf("Search(%q) mismatch (-want +got):\n%s", tt.query, diff)
+			}
+		})
+	}
+
+	results, err := b.Search("定義", 1)
+	if err != nil {
+		t.Errorf("Search(): %v", err)
+		return
+	}
+	want := []*SearchResult{
+		{ID: "c686397e4a0f4f11683d", Title: "Goの関数", Path: goPath, Score: 1, Snippet: "関数は func で定義します。"},
+	}
+	if diff := cmp.Diff(want, results); diff != "" {
+		t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The index follows the changes of the files.
+	if _, err := os.Stat(b.searchIndexPath()); err != nil {
+		t.Errorf("search index is not saved: %v", err)
+	}
+	write("20200423/go.md", "---\nID: c686397e4a0f4f11683d\nTitle: Goのメソッド\nTags: Go:1.14\n---\nメソッドは func で定義します。\n")
+	// The modification time may not change within the resolution of the filesystem.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(goPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(pyPath); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{}, search("関数")); diff != "" {
+		t.Errorf("Search() after update mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{goPath}, search("メソッド")); diff != "" {
+		t.Errorf("Search() after update mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := b.Search(" ", 0); err == nil {
+		t.Errorf("Search() succeeded with an empty query")
+	}
+}
@@ -0,0 +1,129 @@
+package qiisync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// secretRule is a rule to find sensitive data in articles.
+type secretRule struct {
+	name string
+	reg  *regexp.Regexp
+}
+
+var defaultSecretRules = []*secretRule{
+	{name: "aws-access-key", reg: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-key", reg: regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{name: "github-token", reg: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	// A Qiita token is 40 hex digits, which cannot be distinguished from a commit hash by itself.
+	{name: "qiita-token", reg: regexp.MustCompile(`(?i)(?:qiita|bearer|token)\S*\s*[:=]?\s*["']?[0-9a-f]{40}\b`)},
+	{name: "private-key", reg: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{name: "private-ip", reg: regexp.MustCompile(`\b(?:10\.\d{1,3}\.\d{1,3}\.\d{1,3}|172\.(?:1[6-9]|2\d|3[01])\.\d{1,3}\.\d{1,3}|192\.168\.\d{1,3}\.\d{1,3})\b`)},
+}
+
+// SecretFinding is sensitive data found in an article.
+type SecretFinding struct {
+	Rule  string
+	File  string
+	Line  int
+	Match string
+}
+
+func (f *SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Rule, maskSecret(f.Match))
+}
+
+// maskSecret hides most of s so that the secret is not printed in logs as it is.
+func maskSecret(s string) string {
+	r := []rune(s)
+	if len(r) <= 4 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[:4]) + strings.Repeat("*", len(r)-4)
+}
+
+// secretRules returns the default rules and the rules configured in the [secrets] section.
+func (b *Broker) secretRules() ([]*secretRule, error) {
+	disabled := make(map[string]bool, len(b.Secrets.Disable))
+	for _, name := range b.Secrets.Disable {
+		disabled[name] = true
+	}
+
+	var rules []*secretRule
+	for _, r := range defaultSecretRules {
+		if !disabled[r.name] {
+			rules = append(rules, r)
+		}
+	}
+	if b.Qiita.Token != "" && !disabled["qiita-token"] {
+		rules = append(rules, &secretRule{name: "qiita-token", reg: regexp.MustCompile(regexp.QuoteMeta(b.Qiita.Token))})
+	}
+	if len(b.Secrets.EmailDomains) > 0 && !disabled["email"] {
+		domains := make([]string, len(b.Secrets.EmailDomains))
+		for i, d := range b.Secrets.EmailDomains {
+			domains[i] = regexp.QuoteMeta(strings.TrimPrefix(d, "@"))
+		}
+		reg, err := regexp.Compile(`(?i)[A-Za-z0-9._%+-]+@(?:[A-Za-z0-9-]+\.)*(?:` + strings.Join(domains, "|") + `)\b`)
+		if err != nil {
+			return nil, fmt.Errorf("email_domains: %w", err)
+		}
+		rules = append(rules, &secretRule{name: "email", reg: reg})
+	}
+	for _, r := range b.Secrets.Rules {
+		reg, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("secret rule %s: %w", r.Name, err)
+		}
+		rules = append(rules, &secretRule{name: r.Name, reg: reg})
+	}
+	return rules, nil
+}
+
+// ScanSecrets finds sensitive data such as access tokens, private IP addresses and
+// internal email addresses in the title and the body of the article.
+func (b *Broker) ScanSecrets(a *Article) ([]*SecretFinding, error) {
+	rules, err := b.secretRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []*SecretFinding
+	scan := func(line int, text string) {
+		for _, r := range rules {
+			for _, m := range r.reg.FindAllString(text, -1) {
+				findings = append(findings, &SecretFinding{Rule: r.name, File: a.FilePath, Line: line, Match: m})
+			}
+		}
+	}
+	scan(0, a.Title)
+	offset := bodyLineOffset(a.FilePath, a.Item.Body)
+	for i, line := range strings.Split(a.Item.Body, "\n") {
+		scan(i+1+offset, line)
+	}
+	return findings, nil
+}
+
+// checkSecrets returns an error if the article contains sensitive data, unless AllowSecrets is set.
+func (b *Broker) checkSecrets(a *Article) error {
+	findings, err := b.ScanSecrets(a)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	lines := make([]string, len(findings))
+	for i, f := range findings {
+		lines[i] = "  " + f.String()
+	}
+	if b.AllowSecrets {
+		Logf("secret", "posting the article with possible secrets:\n%s", strings.Join(lines, "\n"))
+		return nil
+	}
+	visibility := "private"
+	if !a.Private {
+		visibility = "public"
+	}
+	return fmt.Errorf("%s article contains possible secrets. remove them or use --allow-secrets:\n%s", visibility, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,102 @@
+package qiisync
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const htmlExtension = ".html"
+
+// snapshotTemplate wraps rendered_body of Qiita so that the snapshot can be opened alone.
+var snapshotTemplate = template.Must(template.New("snapshot").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="canonical" href="{{.URL}}">
+</head>
+<body>
+<article>
+<h1>{{.Title}}</h1>
+<p>{{range .Tags}}<span class="tag">{{.Name}}</span> {{end}}</p>
+<p><a href="{{.URL}}">{{.URL}}</a> ({{.UpdatedAt.Format "2006-01-02 15:04:05 MST"}})</p>
+{{.Body}}
+</article>
+</body>
+</html>
+`))
+
+// htmlPath returns the path of the HTML snapshot of the article stored at articlePath.
+// If html_dir is set, snapshots are stored in the directory tree under html_dir that
+// mirrors base_dir. Otherwise they are stored next to the articles.
+func (b *Broker) htmlPath(articlePath string) string {
+	p := strings.TrimSuffix(articlePath, filepath.Ext(articlePath)) + htmlExtension
+	if b.Local.HTMLDir == "" {
+		return p
+	}
+	rel, err := filepath.Rel(b.baseDir(), p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return p
+	}
+	return filepath.Join(b.baseDir(), b.Local.HTMLDir, rel)
+}
+
+// renderSnapshot renders rendered_body of the item in the template of the snapshot.
+func renderSnapshot(item *Item) (string, error) {
+	var buf bytes.Buffer
+	err := snapshotTemplate.Execute(&buf, struct {
+		*Item
+		Body template.HTML
+	}{
+		Item: item,
+		Body: template.HTML(item.RenderedBody),
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// StoreHTML stores rendered_body of the article as the HTML snapshot of the article at articlePath.
+func (b *Broker) StoreHTML(articlePath string, a *Article) error {
+	s, err := renderSnapshot(a.Item)
+	if err != nil {
+		return err
+	}
+	p := b.htmlPath(articlePath)
+	Logf("store", "%s", p)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(p, []byte(s), 0644); err != nil {
+		return err
+	}
+	return os.Chtimes(p, a.Item.UpdatedAt, a.Item.UpdatedAt)
+}
+
+// RenderedHTML returns the HTML of the article rendered by Qiita.
+// The stored snapshot is used if it exists, unless remote is true.
+func (b *Broker) RenderedHTML(a *Article, remote bool) (string, error) {
+	if !remote {
+		d, err := ioutil.ReadFile(b.htmlPath(a.FilePath))
+		if err == nil {
+			return string(d), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	if a.ID == "" {
+		return "", errors.New("article has not been posted yet")
+	}
+	ra, err := b.fetchRemoteArticle(a)
+	if err != nil {
+		return "", err
+	}
+	return renderSnapshot(ra.Item)
+}
@@ -0,0 +1,90 @@
+package qiisync
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		htmlDir string
+		want    string
+	}{
+		{
+			name: "next_to_article",
+			want: filepath.Join("testdata", "broker", "20200422", "はじめてのGo.html"),
+		},
+		{
+			name:    "html_dir",
+			htmlDir: "html",
+			want:    filepath.Join("testdata", "broker", "html", "20200422", "はじめてのGo.html"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Broker{Config: &Config{Local: localConfig{Dir: filepath.Join("testdata", "broker"), HTMLDir: tt.htmlDir}}}
+			got := b.htmlPath(filepath.Join("testdata", "broker", "20200422", "はじめてのGo.md"))
+			if got != tt.want {
+				t.Errorf("htmlPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderedHTML(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id": "c686397e4a0f4f11683d", "title": "Remote title", "rendered_body": "<h1>Remote</h1>"}`)
+	})
+
+	a := &Article{
+		ArticleHeader: &ArticleHeader{ID: "c686397e4a0f4f11683d", Title: "Example title"},
+		Item: &Item{
+			ID:           "c686397e4a0f4f11683d",
+			Title:        "Example title",
+			URL:          "https://qiita.com/Qiita/items/c686397e4a0f4f11683d",
+			RenderedBody: "<h1>Example</h1>",
+			Tags:         []*Tag{{Name: "Go"}},
+			UpdatedAt:    time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		FilePath: filepath.Join(b.baseDir(), "20000101", "Example title.md"),
+	}
+	if err := b.StoreHTML(a.FilePath, a); err != nil {
+		t.Errorf("StoreHTML(): %v", err)
+		return
+	}
+
+	got, err := b.RenderedHTML(a, false)
+	if err != nil {
+		t.Errorf("RenderedHTML(): %v", err)
+		return
+	}
+	for _, want := range []string{"<title>Example title</title>", `<span class="tag">Go</span>`, "<h1>Example</h1>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderedHTML() = %v, want to contain %v", got, want)
+		}
+	}
+
+	got, err = b.RenderedHTML(a, true)
+	if err != nil {
+		t.Errorf("RenderedHTML(): %v", err)
+		return
+	}
+	if !strings.Contains(got, "<h1>Remote</h1>") {
+		t.Errorf("RenderedHTML() = %v, want the HTML of remote", got)
+	}
+}
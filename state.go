@@ -0,0 +1,49 @@
+package qiisync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// stateDirName is the directory under base_dir where qiisync keeps
+	// the information it needs between runs. It is never treated as articles.
+	stateDirName     = ".qiisync"
+	lastPullFileName = "last_pull"
+)
+
+func (b *Broker) stateDir() string {
+	return filepath.Join(b.baseDir(), stateDirName)
+}
+
+// LastPulled returns the updated_at of the newest article stored by the previous pull.
+// If pull has never been completed, it returns the zero value of time.Time.
+func (b *Broker) LastPulled() (time.Time, error) {
+	d, err := ioutil.ReadFile(filepath.Join(b.stateDir(), lastPullFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(d)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse last pull time: %w", err)
+	}
+	return t, nil
+}
+
+// SaveLastPulled records t as the time of the last pull.
+//
+// The updated_at of the remote article is stored rather than the local clock,
+// so that the difference between the clock of Qiita and the local one does not matter.
+func (b *Broker) SaveLastPulled(t time.Time) error {
+	if err := os.MkdirAll(b.stateDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(b.stateDir(), lastPullFileName), []byte(t.Format(time.RFC3339Nano)+"\n"), 0644)
+}
@@ -0,0 +1,46 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLastPulled(t *testing.T) {
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+		return
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	b := &Broker{Config: &Config{Local: localConfig{Dir: tempDir}}}
+
+	got, err := b.LastPulled()
+	if err != nil {
+		t.Errorf("LastPulled(): %v", err)
+		return
+	}
+	if !got.IsZero() {
+		t.Errorf("LastPulled() = %v, want zero value before the first pull", got)
+	}
+
+	want := time.Date(2020, 4, 23, 5, 41, 35, 0, time.UTC)
+	if err := b.SaveLastPulled(want); err != nil {
+		t.Errorf("SaveLastPulled(): %v", err)
+		return
+	}
+	got, err = b.LastPulled()
+	if err != nil {
+		t.Errorf("LastPulled(): %v", err)
+		return
+	}
+	if !got.Equal(want) {
+		t.Errorf("LastPulled() = %v, want %v", got, want)
+	}
+}
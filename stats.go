@@ -0,0 +1,179 @@
+package qiisync
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Songmu/flextime"
+)
+
+const statsHistoryFileName = "stats.csv"
+
+// ArticleStats is the statistics of an article on Qiita.
+type ArticleStats struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	Likes     int       `json:"likes"`
+	Stocks    int       `json:"stocks"`
+	Reactions int       `json:"reactions"`
+	Comments  int       `json:"comments"`
+	PageViews int       `json:"page_views"`
+}
+
+var statsColumns = []string{"id", "title", "created_at", "likes", "stocks", "reactions", "comments", "page_views"}
+
+func (s *ArticleStats) record() []string {
+	return []string{
+		s.ID,
+		s.Title,
+		s.CreatedAt.Format(time.RFC3339),
+		strconv.Itoa(s.Likes),
+		strconv.Itoa(s.Stocks),
+		strconv.Itoa(s.Reactions),
+		strconv.Itoa(s.Comments),
+		strconv.Itoa(s.PageViews),
+	}
+}
+
+// FetchStats extracts the statistics of all articles of the authenticated user from Qiita.
+//
+// The list of the articles does not include page views, which are returned only by
+// GET /items/:id, so each article is fetched as well.
+func (b *Broker) FetchStats() ([]*ArticleStats, error) {
+	articles, err := b.FetchRemoteArticles()
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]*ArticleStats, len(articles))
+	for i, a := range articles {
+		ra, err := b.fetchRemoteArticle(a)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", a.ID, err)
+		}
+		stats[i] = &ArticleStats{
+			ID:        a.Item.ID,
+			Title:     a.Item.Title,
+			URL:       a.Item.URL,
+			CreatedAt: a.Item.CreatedAt,
+			Likes:     a.Item.LikesCount,
+			Stocks:    a.Item.StocksCount,
+			Reactions: a.Item.ReactionsCount,
+			Comments:  a.Item.CommentsCount,
+			PageViews: ra.Item.PageViewsCount,
+		}
+	}
+	return stats, nil
+}
+
+// SortStats sorts the statistics by key in descending order, or ascending order if asc is true.
+// The key is one of the columns of the statistics except id.
+func SortStats(stats []*ArticleStats, key string, asc bool) error {
+	var less func(a, b *ArticleStats) bool
+	switch key {
+	case "title":
+		less = func(a, b *ArticleStats) bool { return a.Title < b.Title }
+	case "created_at":
+		less = func(a, b *ArticleStats) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "likes":
+		less = func(a, b *ArticleStats) bool { return a.Likes < b.Likes }
+	case "stocks":
+		less = func(a, b *ArticleStats) bool { return a.Stocks < b.Stocks }
+	case "reactions":
+		less = func(a, b *ArticleStats) bool { return a.Reactions < b.Reactions }
+	case "comments":
+		less = func(a, b *ArticleStats) bool { return a.Comments < b.Comments }
+	case "page_views":
+		less = func(a, b *ArticleStats) bool { return a.PageViews < b.PageViews }
+	default:
+		return fmt.Errorf("unknown sort key: %s", key)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if asc {
+			return less(stats[i], stats[j])
+		}
+		return less(stats[j], stats[i])
+	})
+	return nil
+}
+
+// WriteStats writes the statistics to w in format, "table", "csv" or "json".
+func WriteStats(w io.Writer, stats []*ArticleStats, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tLIKES\tSTOCKS\tREACTIONS\tCOMMENTS\tPAGE VIEWS\tTITLE")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", s.ID, s.Likes, s.Stocks, s.Reactions, s.Comments, s.PageViews, s.Title)
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(statsColumns); err != nil {
+			return err
+		}
+		for _, s := range stats {
+			if err := cw.Write(s.record()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if stats == nil {
+			stats = []*ArticleStats{}
+		}
+		return enc.Encode(stats)
+	}
+	return fmt.Errorf("unknown format: %s", format)
+}
+
+func (b *Broker) statsHistoryPath() string {
+	return filepath.Join(b.stateDir(), statsHistoryFileName)
+}
+
+// AppendStatsHistory appends the statistics with the current date to the history file,
+// so that the trend of the statistics can be charted.
+func (b *Broker) AppendStatsHistory(stats []*ArticleStats) (string, error) {
+	p := b.statsHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	_, err := os.Stat(p)
+	isNew := os.IsNotExist(err)
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	if isNew {
+		if err := cw.Write(append([]string{"date"}, statsColumns...)); err != nil {
+			return "", err
+		}
+	}
+	date := flextime.Now().Format("2006-01-02")
+	for _, s := range stats {
+		if err := cw.Write(append([]string{date}, s.record()...)); err != nil {
+			return "", err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return "", err
+	}
+	return p, f.Close()
+}
@@ -0,0 +1,220 @@
+package qiisync
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testStats() []*ArticleStats {
+	return []*ArticleStats{
+		{ID: "1111", Title: "B", CreatedAt: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), Likes: 3, PageViews: 10},
+		{ID: "2222", Title: "A", CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Likes: 5, PageViews: 30},
+		{ID: "3333", Title: "C", CreatedAt: time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC), Likes: 1, PageViews: 20},
+	}
+}
+
+func TestFetchStats(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+	})
+
+	// The list of the articles does not include page views.
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		w.Header().Set("Total-Count", "1")
+		fmt.Fprint(w, `
+					[
+						{
+							"created_at": "2000-01-01T00:00:00+00:00",
+							"id": "c686397e4a0f4f11683d",
+							"title": "Example title",
+							"updated_at": "2000-01-01T00:00:00+00:00",
+							"url": "https://qiita.com/Qiita/items/c686397e4a0f4f11683d",
+							"likes_count": 3,
+							"stocks_count": 2,
+							"reactions_count": 1,
+							"comments_count": 4,
+							"page_views_count": null
+						}
+					]
+`)
+	})
+	mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `
+					{
+						"created_at": "2000-01-01T00:00:00+00:00",
+						"id": "c686397e4a0f4f11683d",
+						"title": "Example title",
+						"updated_at": "2000-01-01T00:00:00+00:00",
+						"url": "https://qiita.com/Qiita/items/c686397e4a0f4f11683d",
+						"page_views_count": 100
+					}
+`)
+	})
+
+	got, err := b.FetchStats()
+	if err != nil {
+		t.Errorf("FetchStats(): %v", err)
+		return
+	}
+	want := []*ArticleStats{
+		{
+			ID:        "c686397e4a0f4f11683d",
+			Title:     "Example title",
+			URL:       "https://qiita.com/Qiita/items/c686397e4a0f4f11683d",
+			CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			Likes:     3,
+			Stocks:    2,
+			Reactions: 1,
+			Comments:  4,
+			PageViews: 100,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FetchStats() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSortStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		asc     bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "likes", key: "likes", want: []string{"2222", "1111", "3333"}},
+		{name: "likes asc", key: "likes", asc: true, want: []string{"3333", "1111", "2222"}},
+		{name: "page_views", key: "page_views", want: []string{"2222", "3333", "1111"}},
+		{name: "title asc", key: "title", asc: true, want: []string{"2222", "1111", "3333"}},
+		{name: "created_at", key: "created_at", want: []string{"3333", "1111", "2222"}},
+		{name: "unknown", key: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := testStats()
+			err := SortStats(stats, tt.key, tt.asc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SortStats() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, s := range stats {
+				got = append(got, s.ID)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SortStats() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "csv",
+			format: "csv",
+			want: `id,title,created_at,likes,stocks,reactions,comments,page_views
+1111,B,2000-01-02T00:00:00Z,3,0,0,0,10
+`,
+		},
+		{
+			name:   "json",
+			format: "json",
+			want: `[
+  {
+    "id": "1111",
+    "title": "B",
+    "url": "",
+    "created_at": "2000-01-02T00:00:00Z",
+    "likes": 3,
+    "stocks": 0,
+    "reactions": 0,
+    "comments": 0,
+    "page_views": 10
+  }
+]
+`,
+		},
+		{
+			name:   "table",
+			format: "table",
+			want: `ID    LIKES  STOCKS  REACTIONS  COMMENTS  PAGE VIEWS  TITLE
+1111  3      0       0          0         10          B
+`,
+		},
+		{name: "unknown", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteStats(&buf, testStats()[:1], tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteStats() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("WriteStats() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppendStatsHistory(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		flextime.Restore()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	stats := testStats()[:1]
+	flextime.Fix(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	if _, err := b.AppendStatsHistory(stats); err != nil {
+		t.Errorf("AppendStatsHistory(): %v", err)
+		return
+	}
+	stats[0].Likes = 4
+	flextime.Fix(time.Date(2020, 5, 2, 12, 0, 0, 0, time.UTC))
+	p, err := b.AppendStatsHistory(stats)
+	if err != nil {
+		t.Errorf("AppendStatsHistory(): %v", err)
+		return
+	}
+
+	got, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Errorf("read history: %v", err)
+		return
+	}
+	want := `date,id,title,created_at,likes,stocks,reactions,comments,page_views
+2020-05-01,1111,B,2000-01-02T00:00:00Z,3,0,0,0,10
+2020-05-02,1111,B,2000-01-02T00:00:00Z,4,0,0,0,10
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("AppendStatsHistory() mismatch (-want +got):\n%s", diff)
+	}
+}
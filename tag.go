@@ -0,0 +1,342 @@
+package qiisync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Songmu/flextime"
+)
+
+const (
+	tagCacheFileName    = "tags.json"
+	tagCacheTTL         = 7 * 24 * time.Hour
+	defaultMinFollowers = 10
+	maxTagSuggestions   = 3
+)
+
+// TagInfo is a structure that represents the tag of QiitaAPI.
+//
+// See also https://qiita.com/api/v2/docs#%E3%82%BF%E3%82%B0.
+type TagInfo struct {
+	ID             string `json:"id"`
+	FollowersCount int    `json:"followers_count"`
+	ItemsCount     int    `json:"items_count"`
+	IconURL        string `json:"icon_url"`
+}
+
+// tagCacheEntry is the result of looking up a tag. Tag is nil if the tag does not exist.
+type tagCacheEntry struct {
+	Tag       *TagInfo  `json:"tag"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// TagCount is the number of local articles that use the tag.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// TagIssue is a problem with a tag of an article.
+type TagIssue struct {
+	Tag         string
+	Message     string
+	Suggestions []string
+}
+
+func (i *TagIssue) String() string {
+	if len(i.Suggestions) == 0 {
+		return fmt.Sprintf("%s: %s", i.Tag, i.Message)
+	}
+	return fmt.Sprintf("%s: %s, did you mean %s?", i.Tag, i.Message, strings.Join(i.Suggestions, ", "))
+}
+
+func (c *Config) minFollowers() int {
+	if c.Tags.MinFollowers > 0 {
+		return c.Tags.MinFollowers
+	}
+	return defaultMinFollowers
+}
+
+// FetchTag extracts the tag specified by name from Qiita.
+// If the tag does not exist, it returns nil.
+func (b *Broker) FetchTag(name string) (*TagInfo, error) {
+	if name == "" {
+		return nil, errors.New("tag name is required")
+	}
+	u := fmt.Sprintf("api/v2/tags/%s", url.PathEscape(name))
+	req, err := b.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var tag TagInfo
+	if err := json.NewDecoder(resp.Body).Decode(&tag); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	return &tag, nil
+}
+
+func (b *Broker) tagCachePath() string {
+	return filepath.Join(b.stateDir(), tagCacheFileName)
+}
+
+// loadTagCache returns the results of the previous lookups keyed by the lower-cased tag names,
+// since Qiita does not distinguish the case of tags.
+func (b *Broker) loadTagCache() (map[string]*tagCacheEntry, error) {
+	cache := make(map[string]*tagCacheEntry)
+	d, err := ioutil.ReadFile(b.tagCachePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(d, &cache); err != nil {
+		return nil, fmt.Errorf("json decode: %w", err)
+	}
+	return cache, nil
+}
+
+func (b *Broker) saveTagCache(cache map[string]*tagCacheEntry) error {
+	p := b.tagCachePath()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	d, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p, d, 0644)
+}
+
+// LookupTags returns the tags on Qiita specified by names. The value is nil if the tag does not exist.
+// The results are cached in the state directory for a week.
+func (b *Broker) LookupTags(names []string) (map[string]*TagInfo, error) {
+	cache, err := b.loadTagCache()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := b.lookupTags(cache, names)
+	if err != nil {
+		return nil, err
+	}
+	return tags, b.saveTagCache(cache)
+}
+
+func (b *Broker) lookupTags(cache map[string]*tagCacheEntry, names []string) (map[string]*TagInfo, error) {
+	now := flextime.Now()
+	tags := make(map[string]*TagInfo, len(names))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		e, ok := cache[key]
+		if !ok || now.Sub(e.FetchedAt) > tagCacheTTL {
+			tag, err := b.FetchTag(name)
+			if err != nil {
+				return nil, fmt.Errorf("tag %s: %w", name, err)
+			}
+			e = &tagCacheEntry{Tag: tag, FetchedAt: now}
+			cache[key] = e
+		}
+		tags[name] = e.Tag
+	}
+	return tags, nil
+}
+
+// LocalTagCounts returns the tags used in the local articles, including the ones not posted yet,
+// in descending order of the number of articles.
+func (b *Broker) LocalTagCounts() ([]*TagCount, error) {
+	files, err := b.LocalArticleFiles()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, f := range files {
+		a, err := ArticleFromFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range tagNames(a.Tags) {
+			counts[name]++
+		}
+	}
+
+	tcs := make([]*TagCount, 0, len(counts))
+	for name, n := range counts {
+		tcs = append(tcs, &TagCount{Name: name, Count: n})
+	}
+	sort.Slice(tcs, func(i, j int) bool {
+		if tcs[i].Count != tcs[j].Count {
+			return tcs[i].Count > tcs[j].Count
+		}
+		return tcs[i].Name < tcs[j].Name
+	})
+	return tcs, nil
+}
+
+// tagNames returns the names of the tags in the tag string of the header without versions.
+func tagNames(tagString string) []string {
+	var names []string
+	for _, t := range MarshalTag(tagString) {
+		if name := strings.TrimSpace(t.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CheckTags checks that the tags of the article exist on Qiita and have enough followers.
+// Close matches among the tags looked up so far are suggested for the problematic tags.
+func (b *Broker) CheckTags(a *Article) ([]*TagIssue, error) {
+	names := tagNames(a.Tags)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	cache, err := b.loadTagCache()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := b.lookupTags(cache, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []*TagIssue
+	for _, name := range names {
+		tag := tags[name]
+		switch {
+		case tag == nil:
+			issues = append(issues, &TagIssue{Tag: name, Message: "tag does not exist on Qiita"})
+		case tag.ID != name:
+			issues = append(issues, &TagIssue{Tag: name, Message: fmt.Sprintf("tag is spelled %s on Qiita", tag.ID)})
+		case tag.FollowersCount < b.minFollowers():
+			issues = append(issues, &TagIssue{Tag: name, Message: fmt.Sprintf("tag has only %d followers", tag.FollowersCount)})
+		}
+	}
+	if len(issues) == 0 {
+		return nil, b.saveTagCache(cache)
+	}
+
+	candidates := tagCandidates(cache)
+	for _, i := range issues {
+		followers := 0
+		if tag := tags[i.Tag]; tag != nil {
+			if tag.ID != i.Tag {
+				i.Suggestions = []string{tag.ID}
+				continue
+			}
+			followers = tag.FollowersCount
+		}
+		i.Suggestions = suggestTags(i.Tag, followers, candidates)
+	}
+	return issues, b.saveTagCache(cache)
+}
+
+// tagCandidates returns the existing tags in the cache, which are the tags of the articles checked
+// so far and the local tags looked up by "qiisync tags --check". The tags are not looked up here,
+// since looking up all the local tags on every check easily hits the rate limit of Qiita API.
+func tagCandidates(cache map[string]*tagCacheEntry) []*TagInfo {
+	var candidates []*TagInfo
+	for _, e := range cache {
+		if e.Tag != nil {
+			candidates = append(candidates, e.Tag)
+		}
+	}
+	return candidates
+}
+
+// suggestTags returns the candidates similar to name that have more followers than name.
+func suggestTags(name string, followers int, candidates []*TagInfo) []string {
+	lower := strings.ToLower(name)
+	var similar []*TagInfo
+	for _, c := range candidates {
+		cl := strings.ToLower(c.ID)
+		if cl == lower || c.FollowersCount <= followers {
+			continue
+		}
+		if editDistance(lower, cl) <= 2 || (len(cl) >= 2 && strings.Contains(lower, cl)) || (len(lower) >= 2 && strings.Contains(cl, lower)) {
+			similar = append(similar, c)
+		}
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].FollowersCount != similar[j].FollowersCount {
+			return similar[i].FollowersCount > similar[j].FollowersCount
+		}
+		return similar[i].ID < similar[j].ID
+	})
+
+	var suggestions []string
+	for i := 0; i < len(similar) && i < maxTagSuggestions; i++ {
+		suggestions = append(suggestions, similar[i].ID)
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between s and t.
+func editDistance(s, t string) int {
+	a, b := []rune(s), []rune(t)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	m := a
+	if b < m {
+		m = b
+	}
+	if c < m {
+		m = c
+	}
+	return m
+}
+
+// checkTags logs the problems with the tags of the article.
+// They never prevent the article from being posted, since Qiita accepts any tag.
+func (b *Broker) checkTags(a *Article) {
+	if b.Tags.SkipCheck {
+		return
+	}
+	issues, err := b.CheckTags(a)
+	if err != nil {
+		Logf("tag", "cannot check tags: %v", err)
+		return
+	}
+	for _, i := range issues {
+		Logf("tag", "%s", i)
+	}
+}
@@ -0,0 +1,176 @@
+package qiisync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Songmu/flextime"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckTags(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		flextime.Restore()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	flextime.Fix(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	if err := os.MkdirAll(b.baseDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(b.baseDir(), "go.md"), []byte(`---
+Title: Go
+Tags: Go:1.14,Docker
+---
+# Go
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(b.baseDir(), "aws.md"), []byte("---\nTitle: AWS\nTags: AWS\n---\n# AWS\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := make(map[string]int)
+	tags := map[string]string{
+		"Go":     `{"id": "Go", "followers_count": 20000, "items_count": 10000}`,
+		"go":     `{"id": "Go", "followers_count": 20000, "items_count": 10000}`,
+		"Docker": `{"id": "Docker", "followers_count": 15000, "items_count": 8000}`,
+		"Golang": `{"id": "Golang", "followers_count": 3, "items_count": 100}`,
+		"AWS":    `{"id": "AWS", "followers_count": 12000, "items_count": 9000}`,
+	}
+	mux.HandleFunc("/api/v2/tags/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		name := r.URL.Path[len("/api/v2/tags/"):]
+		requests[name]++
+		tag, ok := tags[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, tag)
+	})
+
+	tests := []struct {
+		name string
+		tags string
+		want []*TagIssue
+	}{
+		{
+			name: "valid",
+			tags: "Go:1.14,Docker",
+		},
+		{
+			name: "low_followers",
+			tags: "Golang",
+			want: []*TagIssue{{Tag: "Golang", Message: "tag has only 3 followers", Suggestions: []string{"Go"}}},
+		},
+		{
+			name: "not_exist",
+			tags: "Dockerr",
+			want: []*TagIssue{{Tag: "Dockerr", Message: "tag does not exist on Qiita", Suggestions: []string{"Docker"}}},
+		},
+		{
+			name: "case",
+			tags: "go",
+			want: []*TagIssue{{Tag: "go", Message: "tag is spelled Go on Qiita", Suggestions: []string{"Go"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.CheckTags(&Article{ArticleHeader: &ArticleHeader{Tags: tt.tags}})
+			if err != nil {
+				t.Errorf("CheckTags(): %v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("CheckTags() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Only the tags of the checked articles are looked up, not all the local tags.
+	if n := requests["AWS"]; n != 0 {
+		t.Errorf("tag AWS used only in another article is fetched %d times, want 0", n)
+	}
+
+	// Every tag is fetched only once thanks to the cache.
+	for name, n := range requests {
+		if n != 1 {
+			t.Errorf("tag %s is fetched %d times, want 1", name, n)
+		}
+	}
+
+	// The cache expires after a while.
+	flextime.Fix(time.Date(2020, 5, 9, 0, 0, 0, 0, time.UTC))
+	if _, err := b.LookupTags([]string{"Go"}); err != nil {
+		t.Errorf("LookupTags(): %v", err)
+	}
+	if requests["Go"] != 2 {
+		t.Errorf("tag Go is fetched %d times after the cache expired, want 2", requests["Go"])
+	}
+}
+
+func TestLocalTagCounts(t *testing.T) {
+	b, _, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	files := map[string]string{
+		"a.md": "---\nTitle: A\nTags: Go:1.14,Docker\n---\n",
+		"b.md": "---\nTitle: B\nTags: Go\n---\n",
+		"c.md": "---\nTitle: C\nTags: AWS\n---\n",
+	}
+	if err := os.MkdirAll(b.baseDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(b.baseDir(), name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := b.LocalTagCounts()
+	if err != nil {
+		t.Errorf("LocalTagCounts(): %v", err)
+		return
+	}
+	want := []*TagCount{
+		{Name: "Go", Count: 2},
+		{Name: "AWS", Count: 1},
+		{Name: "Docker", Count: 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LocalTagCounts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		s, t string
+		want int
+	}{
+		{s: "docker", t: "docker", want: 0},
+		{s: "dockerr", t: "docker", want: 1},
+		{s: "kubernets", t: "kubernetes", want: 1},
+		{s: "golang", t: "go", want: 4},
+		{s: "", t: "go", want: 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.s, tt.t); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.s, tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package qiisync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ImageUploader uploads a local image so that it can be referred from Qiita.
+type ImageUploader interface {
+	// Upload uploads the image of path and returns its public URL.
+	Upload(path string) (string, error)
+}
+
+// commandUploader uploads images with an external command.
+// The command receives the path of the image as the last argument
+// and prints the public URL in the last line of its standard output.
+type commandUploader struct {
+	command string
+}
+
+func (u *commandUploader) Upload(path string) (string, error) {
+	args := strings.Fields(u.command)
+	if len(args) == 0 {
+		return "", errors.New("uploader command is empty")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("uploader command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	uploaded := strings.TrimSpace(lines[len(lines)-1])
+	if !isRemoteLink(uploaded) {
+		return "", fmt.Errorf("uploader command printed %q, not a URL", uploaded)
+	}
+	return uploaded, nil
+}
+
+// httpPutUploader uploads images with HTTP PUT to an S3 compatible endpoint.
+type httpPutUploader struct {
+	b         *Broker
+	putURL    string
+	publicURL string
+}
+
+func (u *httpPutUploader) Upload(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	ext := strings.ToLower(filepath.Ext(path))
+	name := hex.EncodeToString(sum[:])[:16] + ext
+
+	req, err := http.NewRequest(http.MethodPut, withTrailingSlash(u.putURL)+name, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	resp, err := u.b.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("upload %s: %s", path, resp.Status)
+	}
+
+	publicURL := u.publicURL
+	if publicURL == "" {
+		publicURL = u.putURL
+	}
+	return withTrailingSlash(publicURL) + name, nil
+}
+
+func withTrailingSlash(s string) string {
+	if strings.HasSuffix(s, "/") {
+		return s
+	}
+	return s + "/"
+}
+
+// imageUploader returns the uploader configured in the [uploader] section, or nil if there is none.
+func (b *Broker) imageUploader() ImageUploader {
+	switch {
+	case b.Uploader.Command != "":
+		return &commandUploader{command: b.Uploader.Command}
+	case b.Uploader.PutURL != "":
+		return &httpPutUploader{b: b, putURL: b.Uploader.PutURL, publicURL: b.Uploader.PublicURL}
+	}
+	return nil
+}
+
+// localImage is a reference to an image in the local filesystem found in an article.
+type localImage struct {
+	Line int
+	Text string
+	Link string
+}
+
+// findLocalImages finds the references to local images in body except in code blocks.
+func findLocalImages(body string) []localImage {
+	var images []localImage
+	forEachLine(body, func(n int, line string) {
+		for _, link := range imageLinks(line) {
+			if isLocalLink(link) {
+				images = append(images, localImage{Line: n, Text: strings.TrimSpace(line), Link: link})
+			}
+		}
+	})
+	return images
+}
+
+func isLocalLink(link string) bool {
+	return !isRemoteLink(link) && !strings.HasPrefix(link, "//") && !strings.HasPrefix(link, "data:")
+}
+
+// resolveLocalImages uploads the local images in body with the configured uploader and
+// replaces their links with the uploaded URLs. The relative links are resolved from the
+// directory of filePath.
+//
+// Qiita API cannot upload files, so if no uploader is configured,
+// it returns an error listing the lines that refer to local images.
+func (b *Broker) resolveLocalImages(body, filePath string) (string, error) {
+	images := findLocalImages(body)
+	if len(images) == 0 {
+		return body, nil
+	}
+
+	uploader := b.imageUploader()
+	if uploader == nil {
+		offset := bodyLineOffset(filePath, body)
+		lines := make([]string, len(images))
+		for i, img := range images {
+			lines[i] = fmt.Sprintf("  %s:%d: %s", filePath, img.Line+offset, img.Text)
+		}
+		return "", fmt.Errorf("local images cannot be posted to Qiita. configure [uploader] or replace them with URLs:\n%s", strings.Join(lines, "\n"))
+	}
+
+	uploaded := make(map[string]string, len(images))
+	for _, img := range images {
+		if _, exists := uploaded[img.Link]; exists {
+			continue
+		}
+		p := filepath.FromSlash(img.Link)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(filePath), p)
+		}
+		u, err := uploader.Upload(p)
+		if err != nil {
+			return "", err
+		}
+		Logf("image", "%s ---> %s", img.Link, u)
+		uploaded[img.Link] = u
+	}
+	return replaceImageLinks(body, func(link string) string {
+		if u, exists := uploaded[link]; exists {
+			return u
+		}
+		return link
+	}), nil
+}
+
+// bodyLineOffset returns the number of lines preceding body in the file,
+// which is the length of the header.
+func bodyLineOffset(filePath, body string) int {
+	if filePath == "" {
+		return 0
+	}
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0
+	}
+	content := string(b)
+	if !strings.HasSuffix(content, body) {
+		return 0
+	}
+	return strings.Count(content[:len(content)-len(body)], "\n")
+}
@@ -0,0 +1,122 @@
+package qiisync
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindLocalImages(t *testing.T) {
+	body := "# Figures\n\n![](./fig.png)\n![remote](https://example.com/fig.png)\n```md\n![](./in_code.png)\n```\n<img src=\"img/fig2.png\">\n"
+
+	got := findLocalImages(body)
+	want := []localImage{
+		{Line: 3, Text: "![](./fig.png)", Link: "./fig.png"},
+		{Line: 8, Text: `<img src="img/fig2.png">`, Link: "img/fig2.png"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findLocalImages() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveLocalImagesNoUploader(t *testing.T) {
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+		return
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	path := filepath.Join(tempDir, "test.md")
+	content := "---\nID: 1234567890abcdefghij\nTitle: test\nTags: Go\nAuthor: d-tsuji\nPrivate: false\n---\n\n# はじめに\n\n![](./fig.png)\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Errorf("write article: %v", err)
+		return
+	}
+	a, err := ArticleFromFile(path)
+	if err != nil {
+		t.Errorf("ArticleFromFile(): %v", err)
+		return
+	}
+
+	b := &Broker{Config: &Config{}}
+	_, err = b.resolveLocalImages(a.Item.Body, path)
+	if err == nil {
+		t.Errorf("expected error occurred if local images exist without uploader")
+		return
+	}
+	if want := path + ":11: ![](./fig.png)"; !strings.Contains(err.Error(), want) {
+		t.Errorf("resolveLocalImages() error = %v, want to contain %q", err, want)
+	}
+}
+
+func TestResolveLocalImagesPut(t *testing.T) {
+	b, mux, serverURL, teardown := setup()
+	t.Cleanup(func() { teardown() })
+
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+		return
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "fig.png"), []byte("fig"), 0644); err != nil {
+		t.Errorf("write image: %v", err)
+		return
+	}
+
+	var uploaded string
+	mux.HandleFunc("/bucket/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		if got := r.Header.Get("Content-Type"); got != "image/png" {
+			t.Errorf("Content-Type: %v, want image/png", got)
+		}
+		d, _ := ioutil.ReadAll(r.Body)
+		uploaded = string(d)
+		w.WriteHeader(http.StatusOK)
+	})
+	b.Uploader = uploaderConfig{PutURL: serverURL + "/bucket", PublicURL: "https://cdn.example.com/qiisync/"}
+
+	// The link in the code block is a sample of Markdown and must not be replaced.
+	sample := "```md\n![](./fig.png)\n```\n"
+	got, err := b.resolveLocalImages("![](./fig.png)\n"+sample, filepath.Join(tempDir, "test.md"))
+	if err != nil {
+		t.Errorf("resolveLocalImages(): %v", err)
+		return
+	}
+	if uploaded != "fig" {
+		t.Errorf("uploaded image = %q, want %q", uploaded, "fig")
+	}
+	if !strings.HasPrefix(got, "![](https://cdn.example.com/qiisync/") || !strings.HasSuffix(got, ".png)\n"+sample) {
+		t.Errorf("resolveLocalImages() = %v, want the link replaced with the public URL", got)
+	}
+}
+
+func TestCommandUploader(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("printf is not available")
+	}
+	u := &commandUploader{command: `printf https://example.com/%s\n`}
+	got, err := u.Upload("fig.png")
+	if err != nil {
+		t.Errorf("Upload(): %v", err)
+		return
+	}
+	if want := "https://example.com/fig.png"; got != want {
+		t.Errorf("Upload() = %v, want %v", got, want)
+	}
+}
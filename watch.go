@@ -0,0 +1,176 @@
+package qiisync
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultWatchDebounce = 2 * time.Second
+	defaultMinBackoff    = 5 * time.Second
+	defaultMaxBackoff    = 5 * time.Minute
+)
+
+// fileWatcher reports the paths of the files written under a directory.
+type fileWatcher interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+// Watcher pushes the articles under base_dir to Qiita when they are saved.
+// Articles without ID are never posted, and private articles are never made public.
+type Watcher struct {
+	broker *Broker
+
+	// Debounce is how long Watcher waits for the file to be saved again before pushing it.
+	Debounce time.Duration
+	// MinBackoff and MaxBackoff are the range of the time Watcher stops pushing
+	// after Qiita API fails. It is doubled every time the API fails in a row.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NewWatcher creates a Watcher with the default timings.
+func (b *Broker) NewWatcher() *Watcher {
+	return &Watcher{
+		broker:     b,
+		Debounce:   defaultWatchDebounce,
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+	}
+}
+
+// Run watches base_dir until stop is closed.
+func (w *Watcher) Run(stop <-chan struct{}) error {
+	walker, err := w.broker.newWalker(w.broker.baseDir())
+	if err != nil {
+		return err
+	}
+	fw, err := newFileWatcher(w.broker.baseDir(), func(path string) bool {
+		rel, err := relSlash(walker.root, path)
+		if err != nil {
+			return true
+		}
+		skip, _ := walker.skipDir(path, rel)
+		return skip
+	})
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	Logf("watch", "watching %s", w.broker.baseDir())
+	return w.loop(fw.Events(), fw.Errors(), walker.accepts, stop)
+}
+
+// loop pushes the files reported in events after they are left unchanged for Debounce.
+func (w *Watcher) loop(events <-chan string, errs <-chan error, accepts func(string) bool, stop <-chan struct{}) error {
+	interval := w.Debounce / 2
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		pending  = make(map[string]time.Time)
+		backoff  time.Duration
+		resumeAt time.Time
+	)
+	for {
+		select {
+		case <-stop:
+			return nil
+		case p, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if accepts(p) {
+				pending[p] = time.Now()
+			}
+		case err := <-errs:
+			return err
+		case now := <-ticker.C:
+			if now.Before(resumeAt) {
+				continue
+			}
+			for p, saved := range pending {
+				if now.Sub(saved) < w.Debounce {
+					continue
+				}
+				delete(pending, p)
+				err := w.push(p)
+				if err == nil {
+					backoff = 0
+					continue
+				}
+				Logf("error", "%s: %v", p, err)
+				if isTemporary(err) {
+					backoff = w.nextBackoff(backoff)
+					resumeAt = now.Add(backoff)
+					pending[p] = now
+					Logf("watch", "Qiita API failed. retry in %s", backoff)
+					break
+				}
+			}
+		}
+	}
+}
+
+func (w *Watcher) nextBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return w.MinBackoff
+	}
+	backoff *= 2
+	if backoff > w.MaxBackoff {
+		return w.MaxBackoff
+	}
+	return backoff
+}
+
+// push pushes the article at path to Qiita with the same checks as update.
+func (w *Watcher) push(path string) error {
+	a, err := ArticleFromFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if a.ID == "" {
+		Logf("watch", "%s has not been posted yet. use post to post it", path)
+		return nil
+	}
+	updated, err := w.broker.UploadFresh(a)
+	if err != nil {
+		return err
+	}
+	if updated {
+		Logf("watch", "pushed %s", path)
+	}
+	return nil
+}
+
+// isTemporary reports whether err is worth retrying, such as a network error or
+// an error response of Qiita API because of the rate limit or the server.
+func isTemporary(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func relSlash(root, path string) (string, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,149 @@
+//go:build linux
+// +build linux
+
+package qiisync
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyWatcher watches a directory tree with inotify(7).
+type inotifyWatcher struct {
+	// fd is kept apart from f, since f.Fd puts the file into the blocking mode.
+	fd      int
+	f       *os.File
+	skipDir func(path string) bool
+
+	mu   sync.Mutex
+	dirs map[int32]string
+
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE_SELF
+
+// newFileWatcher watches dir and its subdirectories except the ones skipDir reports.
+// The directories created later are watched as well.
+func newFileWatcher(dir string, skipDir func(path string) bool) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		fd: fd,
+		// A non-blocking file is read through the runtime poller, so that Close unblocks Read.
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		skipDir: skipDir,
+		dirs:    make(map[int32]string),
+		events:  make(chan string),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+	if err := w.addTree(dir); err != nil {
+		w.f.Close()
+		return nil, err
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string { return w.events }
+func (w *inotifyWatcher) Errors() <-chan error  { return w.errors }
+
+func (w *inotifyWatcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	close(w.done)
+	return w.f.Close()
+}
+
+// addTree watches dir and its subdirectories.
+func (w *inotifyWatcher) addTree(dir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	w.mu.Lock()
+	w.dirs[int32(wd)] = dir
+	w.mu.Unlock()
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		p := filepath.Join(dir, f.Name())
+		if !f.IsDir() || w.skipDir(p) {
+			continue
+		}
+		if err := w.addTree(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) readEvents() {
+	defer close(w.events)
+
+	buf := make([]byte, syscall.SizeofInotifyEvent*4096)
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.errors <- err
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(ev.Len)]
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			offset += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			w.mu.Lock()
+			dir, ok := w.dirs[ev.Wd]
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.dirs, ev.Wd)
+			}
+			w.mu.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+
+			p := filepath.Join(dir, name)
+			if ev.Mask&syscall.IN_ISDIR != 0 {
+				if ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !w.skipDir(p) {
+					if err := w.addTree(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+						w.errors <- err
+						return
+					}
+				}
+				continue
+			}
+			if ev.Mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO) == 0 {
+				continue
+			}
+			select {
+			case w.events <- p:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+//go:build linux
+// +build linux
+
+package qiisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_inotifyWatcher(t *testing.T) {
+	tempDir, err := ioutil.TempDir("testdata", "temp")
+	if err != nil {
+		t.Errorf("create tempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+	if err := os.MkdirAll(filepath.Join(tempDir, stateDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newFileWatcher(tempDir, func(path string) bool {
+		return filepath.Base(path) == stateDirName
+	})
+	if err != nil {
+		t.Errorf("newFileWatcher(): %v", err)
+		return
+	}
+	defer w.Close()
+
+	// The files in the skipped directory are not reported.
+	if err := ioutil.WriteFile(filepath.Join(tempDir, stateDirName, "last_pull"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The directory created after watching is watched as well.
+	sub := filepath.Join(tempDir, "20200423")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	want := filepath.Join(sub, "article.md")
+	if err := writeFileAtomic(want, []byte("# Example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The temporary files of atomic writes are reported too, and filtered later by loop.
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case got := <-w.Events():
+			if filepath.Base(filepath.Dir(got)) == stateDirName {
+				t.Errorf("event for %s in the skipped directory", got)
+			}
+			if got == want {
+				return
+			}
+		case err := <-w.Errors():
+			t.Errorf("watch: %v", err)
+			return
+		case <-timeout:
+			t.Errorf("no event for %s", want)
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+//go:build !linux
+// +build !linux
+
+package qiisync
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const pollInterval = time.Second
+
+// pollWatcher watches a directory tree by comparing the modification times periodically,
+// on the platforms where inotify is not available.
+type pollWatcher struct {
+	dir     string
+	skipDir func(path string) bool
+	mtimes  map[string]time.Time
+
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+// newFileWatcher watches dir and its subdirectories except the ones skipDir reports.
+func newFileWatcher(dir string, skipDir func(path string) bool) (fileWatcher, error) {
+	w := &pollWatcher{
+		dir:     dir,
+		skipDir: skipDir,
+		events:  make(chan string),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+	mtimes, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.mtimes = mtimes
+	go w.poll()
+	return w, nil
+}
+
+func (w *pollWatcher) Events() <-chan string { return w.events }
+func (w *pollWatcher) Errors() <-chan error  { return w.errors }
+
+func (w *pollWatcher) Close() error {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
+	return nil
+}
+
+func (w *pollWatcher) scan() (map[string]time.Time, error) {
+	mtimes := make(map[string]time.Time)
+	err := filepath.Walk(w.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if path != w.dir && w.skipDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		mtimes[path] = info.ModTime()
+		return nil
+	})
+	return mtimes, err
+}
+
+func (w *pollWatcher) poll() {
+	defer close(w.events)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		mtimes, err := w.scan()
+		if err != nil {
+			w.errors <- err
+			return
+		}
+		for p, mtime := range mtimes {
+			if prev, ok := w.mtimes[p]; ok && prev.Equal(mtime) {
+				continue
+			}
+			select {
+			case w.events <- p:
+			case <-w.done:
+				return
+			}
+		}
+		w.mtimes = mtimes
+	}
+}
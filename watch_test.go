@@ -0,0 +1,150 @@
+package qiisync
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcherLoop(t *testing.T) {
+	b, mux, _, teardown := setup()
+	t.Cleanup(func() {
+		teardown()
+		if err := os.RemoveAll(b.baseDir()); err != nil {
+			t.Errorf("remove tempDir: %v", err)
+		}
+	})
+
+	if err := os.MkdirAll(b.baseDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	posted := filepath.Join(b.baseDir(), "posted.md")
+	if err := ioutil.WriteFile(posted, []byte("---\nID: c686397e4a0f4f11683d\nTitle: Example title\nTags: Go\n---\n# Example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	draft := filepath.Join(b.baseDir(), "draft.md")
+	if err := ioutil.WriteFile(draft, []byte("---\nTitle: Draft\nTags: Go\n---\n# Draft\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu      sync.Mutex
+		patches int
+		failed  bool
+	)
+	mux.HandleFunc("/api/v2/items", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("article without ID is posted")
+	})
+	mux.HandleFunc("/api/v2/items/c686397e4a0f4f11683d", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == "PATCH" {
+			// The first push fails as if Qiita were down.
+			if !failed {
+				failed = true
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			patches++
+		}
+		fmt.Fprint(w, `
+					{
+						"body": "# Example",
+						"id": "c686397e4a0f4f11683d",
+						"private": false,
+						"title": "Example title",
+						"updated_at": "2020-04-23T05:41:35+00:00",
+						"url": "https://localhost/Test/items/c686397e4a0f4f11683d"
+					}
+`)
+	})
+
+	w := b.NewWatcher()
+	w.Debounce = 20 * time.Millisecond
+	w.MinBackoff = 50 * time.Millisecond
+
+	walker, err := b.newWalker(b.baseDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan string)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- w.loop(events, nil, walker.accepts, stop)
+	}()
+
+	// Rapid saves are pushed at once.
+	for i := 0; i < 3; i++ {
+		events <- posted
+	}
+	events <- draft
+	events <- filepath.Join(b.baseDir(), stateDirName, "tags.json")
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := patches
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("loop(): %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !failed || patches != 1 {
+		t.Errorf("loop() failed = %v, patches = %d, want true, 1", failed, patches)
+	}
+}
+
+func Test_isTemporary(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "server", err: &APIError{StatusCode: 503, Status: "503 Service Unavailable"}, want: true},
+		{name: "rate_limit", err: fmt.Errorf("fetch: %w", &APIError{StatusCode: 429, Status: "429 Too Many Requests"}), want: true},
+		{name: "not_found", err: &APIError{StatusCode: 404, Status: "404 Not Found"}, want: false},
+		{name: "network", err: &url.Error{Op: "Get", URL: "https://qiita.com/", Err: errors.New("connection refused")}, want: true},
+		{name: "lint", err: errors.New("article has 1 lint errors"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTemporary(tt.err); got != tt.want {
+				t.Errorf("isTemporary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcherNextBackoff(t *testing.T) {
+	w := &Watcher{MinBackoff: time.Second, MaxBackoff: 3 * time.Second}
+	var got []time.Duration
+	var backoff time.Duration
+	for i := 0; i < 4; i++ {
+		backoff = w.nextBackoff(backoff)
+		got = append(got, backoff)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("nextBackoff() = %v, want %v", got, want)
+			break
+		}
+	}
+}